@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"song-sc/internal/cache"
+	"song-sc/internal/crawlstate"
+	"song-sc/internal/health"
+	"song-sc/internal/logging"
+	"song-sc/internal/manifest"
+	"song-sc/internal/metrics"
+	"song-sc/internal/model"
+	"song-sc/internal/page"
+	"song-sc/internal/profile"
+	"song-sc/internal/queue"
+	"song-sc/internal/shutdown"
+	"song-sc/internal/store"
+)
+
+const defaultWorkers = 3
+
+const (
+	defaultEntityCachePath = "entities.jsonl"
+	defaultEntityCacheTTL  = 24 * time.Hour
+
+	defaultItemStatePath = "item-state.jsonl"
+	defaultManifestDir   = "manifests"
+	defaultMetricsAddr   = ":9102"
+
+	// defaultShutdownGrace is how long items in flight may take to finish after a shutdown
+	// signal, it has to stay below the stop timeout of the container.
+	defaultShutdownGrace = 30 * time.Second
+
+	// manifestInterval is how often the manifests are written while items are processed,
+	// so a killed process still leaves them behind.
+	manifestInterval = time.Minute
+)
+
+func main() {
+	os.Exit(consume())
+}
+
+// consume runs the workers until a shutdown signal and returns the exit status. It is
+// separate from main so deferred cleanup runs before the process exits.
+func consume() int {
+	full := flag.Bool("full", false, "save every item, not only new and changed ones")
+	flag.Parse()
+	if err := logging.Setup("detail"); err != nil {
+		log.Fatal(err)
+	}
+
+	rabbitUrl := os.Getenv("RABBITMQ_URL")
+	baseURL := os.Getenv("SONGSARA_BASE_URL")
+
+	selectors, err := profile.Load(os.Getenv("SELECTOR_PROFILE"))
+	if err != nil {
+		logging.Fatal("could not load the selector profile", "error", err)
+	}
+
+	entityCachePath := os.Getenv("ENTITY_CACHE_PATH")
+	if entityCachePath == "" {
+		entityCachePath = defaultEntityCachePath
+	}
+	entityCacheTTL := defaultEntityCacheTTL
+	if v := os.Getenv("ENTITY_CACHE_TTL"); v != "" {
+		entityCacheTTL, err = time.ParseDuration(v)
+		if err != nil {
+			logging.Fatal("invalid ENTITY_CACHE_TTL", "value", v, "error", err)
+		}
+	}
+	entities, err := cache.Open(entityCachePath, entityCacheTTL)
+	if err != nil {
+		logging.Fatal("could not open the entity cache", "error", err)
+	}
+	defer entities.Close()
+	if *full {
+		if err := entities.Purge(); err != nil {
+			logging.Fatal("could not purge the entity cache", "error", err)
+		}
+	}
+
+	sink, err := store.Open(store.ConfigFromEnv())
+	if err != nil {
+		logging.Fatal("could not open the sink", "error", err)
+	}
+	defer sink.Close()
+
+	itemStatePath := os.Getenv("ITEM_STATE_PATH")
+	if itemStatePath == "" {
+		itemStatePath = defaultItemStatePath
+	}
+	itemState, err := crawlstate.Open(itemStatePath)
+	if err != nil {
+		logging.Fatal("could not open the item state", "error", err)
+	}
+	defer itemState.Close()
+
+	manifestDir := os.Getenv("MANIFEST_DIR")
+	if manifestDir == "" {
+		manifestDir = defaultManifestDir
+	}
+	runs := manifest.NewRuns(manifestDir, "detail", *full)
+
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = defaultMetricsAddr
+	}
+	checks := health.New()
+	checks.Ready("storage", sink.Ping)
+	mux := http.NewServeMux()
+	checks.Register(mux)
+	metrics.Serve(metricsAddr, mux)
+
+	shutdownGrace := defaultShutdownGrace
+	if v := os.Getenv("SHUTDOWN_GRACE"); v != "" {
+		shutdownGrace, err = time.ParseDuration(v)
+		if err != nil {
+			logging.Fatal("invalid SHUTDOWN_GRACE", "value", v, "error", err)
+		}
+	}
+	// stop ends consumption on a signal, work keeps the items in flight going for up to
+	// shutdownGrace longer
+	stop, exitCode := shutdown.Notify(context.Background())
+	work, abandon := context.WithCancel(context.Background())
+	defer abandon()
+	go func() {
+		<-stop.Done()
+		checks.Stopping()
+		select {
+		case <-time.After(shutdownGrace):
+			slog.Warn("Items are still in flight after the grace period, abandoning them", "grace", shutdownGrace)
+			abandon()
+		case <-work.Done():
+		}
+	}()
+
+	conn, err := queue.Dial(stop, rabbitUrl)
+	if err != nil {
+		logging.Fatal("could not connect to RabbitMQ", "error", err)
+	}
+	defer conn.Close()
+	checks.Ready("broker", conn.Ping)
+
+	workers := defaultWorkers
+	if v := os.Getenv("DETAIL_WORKERS"); v != "" {
+		workers, err = strconv.Atoi(v)
+		if err != nil || workers < 1 {
+			logging.Fatal("invalid DETAIL_WORKERS", "value", v)
+		}
+	}
+
+	fetcherConfig := page.ConfigFromEnv()
+	fetchers := make([]page.Fetcher, 0, workers)
+	defer func() {
+		for _, fetcher := range fetchers {
+			_ = fetcher.Close()
+		}
+	}()
+	for i := range workers {
+		fetcherConfig.Instance = i
+		fetcher, err := page.Open(fetcherConfig)
+		if err != nil {
+			slog.Error("could not open fetcher", logging.KeyWorker, i, "error", err)
+			return 1
+		}
+		fetchers = append(fetchers, fetcher)
+		checks.Live(fmt.Sprintf("fetcher-%d", i), fetcher.Ping)
+	}
+
+	deliveries := queue.Items.Consume(stop, conn, max(workers, 3))
+
+	var wg sync.WaitGroup
+	for i, fetcher := range fetchers {
+		w := &worker{id: i, conn: conn, fetcher: fetcher, selectors: selectors, entities: entities, sink: sink, state: itemState, full: *full, manifests: runs, health: checks, baseURL: baseURL}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.run(stop, work, deliveries)
+		}()
+	}
+	checks.Started()
+	slog.Info("Started workers", "workers", workers)
+	stopped := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(manifestInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopped:
+				return
+			case <-ticker.C:
+				if _, err := runs.Write(false); err != nil {
+					slog.Warn("could not write the run manifests", "error", err)
+				}
+			}
+		}
+	}()
+	wg.Wait()
+	close(stopped)
+	slog.Info("All workers stopped")
+
+	paths, err := runs.Write(work.Err() != nil)
+	if err != nil {
+		slog.Error("could not write the run manifests", "error", err)
+		return 1
+	}
+	slog.Info("Wrote the run manifests", "paths", paths)
+	return exitCode()
+}
+
+// rebase moves rawURL onto the scheme and host of baseURL, so messages produced against
+// the real site can be replayed against a local copy of it.
+func rebase(rawURL, baseURL string) string {
+	if baseURL == "" {
+		return rawURL
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Scheme = base.Scheme
+	u.Host = base.Host
+	return u.String()
+}
+
+// GetAndSaveArtists fetches the page of every linked artist not yet in the cache and stores
+// it once per run, returning the links to the artists that were saved.
+func GetAndSaveArtists(ctx context.Context, run string, fetcher page.Fetcher, links []taxonLink, selectors *profile.Profile, entities *cache.Cache, sink store.Sink) ([]taxonLink, error) {
+	saved := make([]taxonLink, 0, len(links))
+	for _, link := range links {
+		if link.Link == "" {
+			return nil, fmt.Errorf("artist %q has no link", link.NameEN)
+		}
+		artist, _, err := cache.Get(entities, "artist", link.Link, func() (model.Artist, error) {
+			artistDoc, err := fetcher.Fetch(ctx, link.Link)
+			if err != nil {
+				return model.Artist{}, err
+			}
+			imageDiv, err := selectors.Artist.ImageBox.Find(artistDoc.Element)
+			if err != nil {
+				return model.Artist{}, err
+			}
+			imgTag, err := selectors.Artist.Image.Find(imageDiv)
+			if err != nil {
+				return model.Artist{}, err
+			}
+			img, err := imgTag.GetAttribute(selectors.Attributes.Image)
+			if err != nil {
+				return model.Artist{}, err
+			}
+
+			descriptionTag, err := selectors.Artist.DescriptionBox.Find(artistDoc.Element)
+			if err != nil {
+				return model.Artist{}, err
+			}
+			var description string
+			descriptionP, err := selectors.Artist.Description.Find(descriptionTag)
+			if err != nil {
+				logging.From(ctx).Debug("artist has no description", "artist", link.NameEN)
+			} else {
+				description, err = descriptionP.Text()
+				if err != nil {
+					return model.Artist{}, err
+				}
+			}
+
+			return model.Artist{
+				ID:          link.ID,
+				NameEN:      link.NameEN,
+				NameFA:      link.NameFA,
+				Description: description,
+				Img:         img,
+			}, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = entities.Store(run, "artist", link.Link, func() error { return sink.SaveArtist(artist) })
+		if err != nil {
+			return nil, err
+		}
+
+		saved = append(saved, link)
+	}
+	return saved, nil
+}
+
+// GetAndSaveInstrument fetches the page of every linked instrument not yet in the cache and
+// stores it once per run, returning the links to the instruments that were saved.
+func GetAndSaveInstrument(ctx context.Context, run string, fetcher page.Fetcher, links []taxonLink, selectors *profile.Profile, entities *cache.Cache, sink store.Sink) ([]taxonLink, error) {
+	saved := make([]taxonLink, 0, len(links))
+	for _, link := range links {
+		if link.Link == "" {
+			return nil, fmt.Errorf("instrument %q has no link", link.NameEN)
+		}
+		instrument, _, err := cache.Get(entities, "instrument", link.Link, func() (model.Instrument, error) {
+			instrumentDoc, err := fetcher.Fetch(ctx, link.Link)
+			if err != nil {
+				return model.Instrument{}, err
+			}
+
+			descriptionTag, err := selectors.Instrument.DescriptionBox.Find(instrumentDoc.Element)
+			if err != nil {
+				return model.Instrument{}, err
+			}
+			descriptionP, err := selectors.Instrument.Description.Find(descriptionTag)
+			if err != nil {
+				return model.Instrument{}, err
+			}
+			description, err := descriptionP.Text()
+			if err != nil {
+				return model.Instrument{}, err
+			}
+
+			return model.Instrument{
+				ID:          link.ID,
+				NameEN:      link.NameEN,
+				NameFA:      link.NameFA,
+				Description: description,
+			}, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = entities.Store(run, "instrument", link.Link, func() error { return sink.SaveInstrument(instrument) })
+		if err != nil {
+			return nil, err
+		}
+
+		saved = append(saved, link)
+	}
+	return saved, nil
+}
+
+// GetAndSaveGenre stores every linked genre once per run, genres have no page of their own.
+func GetAndSaveGenre(run string, links []taxonLink, entities *cache.Cache, sink store.Sink) ([]taxonLink, error) {
+	saved := make([]taxonLink, 0, len(links))
+	for _, link := range links {
+		key := link.Link
+		if key == "" {
+			key = link.NameEN
+		}
+		genre := model.Genre{ID: link.ID, NameEN: link.NameEN, NameFA: link.NameFA}
+		if err := entities.Store(run, "genre", key, func() error { return sink.SaveGenre(genre) }); err != nil {
+			return nil, err
+		}
+		saved = append(saved, link)
+	}
+
+	return saved, nil
+}
+
+func GetAndSavePublisher(run string, link taxonLink, entities *cache.Cache, sink store.Sink) (taxonLink, error) {
+	if link.NameEN == "" {
+		return taxonLink{}, nil
+	}
+	// publishers have no page of their own, their name is the key
+	pub := model.Publisher{ID: link.ID, NameEN: link.NameEN}
+	if err := entities.Store(run, "publisher", link.NameEN, func() error { return sink.SavePublisher(pub) }); err != nil {
+		return taxonLink{}, err
+	}
+	return link, nil
+}
+
+// GetAndSaveMood stores every linked mood once per run.
+func GetAndSaveMood(run string, links []taxonLink, entities *cache.Cache, sink store.Sink) ([]taxonLink, error) {
+	saved := make([]taxonLink, 0, len(links))
+	for _, link := range links {
+		key := link.Link
+		if key == "" {
+			key = link.NameEN
+		}
+		mood := model.MoodData{ID: link.ID, NameEN: link.NameEN, NameFA: link.NameFA}
+		if err := entities.Store(run, "mood", key, func() error { return sink.SaveMood(mood) }); err != nil {
+			return nil, err
+		}
+		saved = append(saved, link)
+	}
+
+	return saved, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"song-sc/internal/logging"
+	"song-sc/internal/model"
+	"song-sc/internal/page"
+	"song-sc/internal/profile"
+)
+
+// itemSnapshot is everything the detail stage needs from an item page. It is extracted in
+// one pass right after the page is fetched, linked artist and instrument pages are fetched
+// afterwards from the links recorded here. IDs of the item, its tracks and the entities it
+// links to are assigned here as well.
+type itemSnapshot struct {
+	ID          string
+	URL         string
+	Artists     []taxonLink
+	Instruments []taxonLink
+	Genres      []taxonLink
+	Moods       []taxonLink
+	Publisher   taxonLink
+	Tracks      []trackInfo
+}
+
+// taxonLink is a link from an item page to an artist, instrument, genre, mood or publisher.
+type taxonLink struct {
+	ID     string
+	NameEN string
+	NameFA string
+	Link   string
+}
+
+// trackInfo holds the attributes of a track in the item player.
+type trackInfo struct {
+	ID       string
+	Title    string
+	Artist   string
+	Album    string
+	Info     string
+	Image    string
+	Duration string
+	MP3Link  string
+}
+
+// snapshotItem extracts the item page in doc, fetched for the item at itemURL. IDs derive
+// from itemURL, the URL of the message, not from doc.URL which a base URL override or a
+// redirect changes. Missing taxonomy groups are logged and left empty, a missing player is
+// an error since there is nothing to store without tracks.
+func snapshotItem(ctx context.Context, doc *page.Document, itemURL string, selectors *profile.Profile) (*itemSnapshot, error) {
+	snapshot := &itemSnapshot{ID: model.EntityID(model.KindItem, itemURL), URL: itemURL}
+	logger := logging.From(ctx)
+
+	var err error
+	snapshot.Artists, err = taxonLinks(doc, selectors, selectors.Item.Artists, model.KindArtist, false)
+	if err != nil {
+		logger.Warn("failed to find artists", "error", err)
+	}
+	snapshot.Instruments, err = taxonLinks(doc, selectors, selectors.Item.Instruments, model.KindInstrument, false)
+	if err != nil {
+		logger.Warn("failed to find instruments", "error", err)
+	}
+	snapshot.Genres, err = taxonLinks(doc, selectors, selectors.Item.Genres, model.KindGenre, true)
+	if err != nil {
+		logger.Warn("failed to find genres", "error", err)
+	}
+	snapshot.Moods, err = taxonLinks(doc, selectors, selectors.Item.Moods, model.KindMood, true)
+	if err != nil {
+		logger.Warn("failed to find mood data", "error", err)
+	}
+
+	publishers, err := selectors.Item.Publisher.FindAll(doc.Element)
+	if err != nil {
+		logger.Warn("failed to find publisher", "error", err)
+	} else if len(publishers) == 0 {
+		logger.Debug("item has no publisher")
+	} else if name, err := publishers[0].Text(); err != nil {
+		logger.Warn("failed to read publisher", "error", err)
+	} else {
+		// publishers have no page of their own, their name is the slug
+		snapshot.Publisher = taxonLink{ID: model.EntityID(model.KindPublisher, name), NameEN: name}
+	}
+
+	player, err := selectors.Item.Player.Find(doc.Element)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find aramplayer: %w", err)
+	}
+	trackList, err := selectors.Item.TrackList.Find(player)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find ul element: %w", err)
+	}
+	tracks, err := selectors.Item.Track.FindAll(trackList)
+	if err == nil && len(tracks) == 0 {
+		err = selectors.Item.Track.Mismatch(trackList)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find li elements: %w", err)
+	}
+	for i, track := range tracks {
+		var t trackInfo
+		t.Title, _ = track.GetAttribute(selectors.Attributes.TrackTitle)
+		t.Artist, _ = track.GetAttribute(selectors.Attributes.TrackArtist)
+		t.Album, _ = track.GetAttribute(selectors.Attributes.TrackAlbum)
+		t.Info, _ = track.GetAttribute(selectors.Attributes.TrackInfo)
+		t.Image, _ = track.GetAttribute(selectors.Attributes.TrackImage)
+		t.Duration, _ = track.GetAttribute(selectors.Attributes.TrackDuration)
+		t.MP3Link, _ = track.GetAttribute(selectors.Attributes.TrackSrc)
+		if t.MP3Link != "" {
+			t.ID = model.EntityID(model.KindTrack, t.MP3Link)
+		} else {
+			t.ID = model.EntityID(model.KindTrack, fmt.Sprintf("%s#%d", itemURL, i+1))
+		}
+		snapshot.Tracks = append(snapshot.Tracks, t)
+	}
+	return snapshot, nil
+}
+
+// taxonLinks reads the links inside the box matched by group. When strict is set, a link
+// without both names fails the whole group. Links without an href get an ID derived from
+// their name.
+func taxonLinks(doc *page.Document, selectors *profile.Profile, group profile.Selector, kind string, strict bool) ([]taxonLink, error) {
+	box, err := group.Find(doc.Element)
+	if err != nil {
+		return nil, err
+	}
+	anchors, err := selectors.Item.TaxonLink.FindAll(box)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]taxonLink, 0, len(anchors))
+	for _, a := range anchors {
+		nameFA, faErr := a.GetAttribute(selectors.Attributes.NameFA)
+		nameEN, enErr := a.Text()
+		if strict {
+			if faErr != nil {
+				return nil, faErr
+			}
+			if enErr != nil {
+				return nil, enErr
+			}
+		}
+		link, _ := a.GetAttribute(selectors.Attributes.Link)
+		ref := link
+		if ref == "" {
+			ref = nameEN
+		}
+		links = append(links, taxonLink{ID: model.EntityID(kind, ref), NameEN: nameEN, NameFA: nameFA, Link: link})
+	}
+	return links, nil
+}
+
+func linkNames(links []taxonLink) []string {
+	names := make([]string, 0, len(links))
+	for _, link := range links {
+		names = append(names, link.NameEN)
+	}
+	return names
+}
+
+func linkIDs(links []taxonLink) []string {
+	ids := make([]string, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.ID)
+	}
+	return ids
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"song-sc/internal/model"
+	"song-sc/internal/page"
+	"song-sc/internal/profile"
+)
+
+const fixtureBase = "http://127.0.0.1:18080"
+
+func loadProfile(t *testing.T) *profile.Profile {
+	t.Helper()
+	selectors, err := profile.Load("")
+	if err != nil {
+		t.Fatalf("profile.Load() error = %v", err)
+	}
+	return selectors
+}
+
+func TestSnapshotItemReplay(t *testing.T) {
+	selectors := loadProfile(t)
+	replayer := page.NewReplayer(page.Archive("../../testdata/fixtures"))
+
+	tests := []struct {
+		url         string
+		artists     []string
+		instruments []string
+		genres      []string
+		moods       []string
+		publisher   string
+		tracks      []string
+	}{
+		{
+			url:         fixtureBase + "/item/item-1/",
+			artists:     []string{"Artist 1"},
+			instruments: []string{"Instrument 1", "Instrument 2"},
+			genres:      []string{"Genre 1"},
+			moods:       []string{"Mood 1"},
+			publisher:   "Publisher 1",
+			tracks:      []string{"Item 1 - Track 1", "Item 1 - Track 2", "Item 1 - Track 3"},
+		},
+		{
+			url:         fixtureBase + "/item/item-2/",
+			artists:     []string{"Artist 2"},
+			instruments: []string{"Instrument 2", "Instrument 3"},
+			genres:      []string{"Genre 2"},
+			moods:       []string{"Mood 1"},
+			publisher:   "Publisher 2",
+			tracks:      []string{"Item 2 - Track 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			doc, err := replayer.Fetch(context.Background(), tt.url)
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			// the page was fetched from the fixture base, the message names the item on the site
+			itemURL := strings.Replace(tt.url, fixtureBase, "https://songsara.net", 1)
+			snapshot, err := snapshotItem(context.Background(), doc, itemURL, selectors)
+			if err != nil {
+				t.Fatalf("snapshotItem() error = %v", err)
+			}
+
+			if snapshot.ID != model.EntityID(model.KindItem, itemURL) || snapshot.URL != itemURL {
+				t.Errorf("ID, URL = %q, %q, want the ID of %q", snapshot.ID, snapshot.URL, itemURL)
+			}
+			groups := []struct {
+				name  string
+				links []taxonLink
+				want  []string
+			}{
+				{"artists", snapshot.Artists, tt.artists},
+				{"instruments", snapshot.Instruments, tt.instruments},
+				{"genres", snapshot.Genres, tt.genres},
+				{"moods", snapshot.Moods, tt.moods},
+			}
+			for _, g := range groups {
+				if got := linkNames(g.links); strings.Join(got, ",") != strings.Join(g.want, ",") {
+					t.Errorf("%s = %q, want %q", g.name, got, g.want)
+				}
+				for _, link := range g.links {
+					if link.NameFA == "" || link.Link == "" {
+						t.Errorf("%s link %+v is incomplete", g.name, link)
+					}
+				}
+			}
+			if snapshot.Publisher.NameEN != tt.publisher || snapshot.Publisher.ID != model.EntityID(model.KindPublisher, tt.publisher) {
+				t.Errorf("Publisher = %+v, want %q", snapshot.Publisher, tt.publisher)
+			}
+
+			if len(snapshot.Tracks) != len(tt.tracks) {
+				t.Fatalf("found %d tracks, want %d", len(snapshot.Tracks), len(tt.tracks))
+			}
+			for i, track := range snapshot.Tracks {
+				if track.Title != tt.tracks[i] || track.Duration == "" || track.MP3Link == "" {
+					t.Errorf("track %d = %+v, want %q", i, track, tt.tracks[i])
+				}
+				if track.ID != model.EntityID(model.KindTrack, track.MP3Link) {
+					t.Errorf("track %d ID = %q, want the ID of %q", i, track.ID, track.MP3Link)
+				}
+			}
+		})
+	}
+}
+
+func TestSnapshotItemWithoutPlayer(t *testing.T) {
+	selectors := loadProfile(t)
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"no player", `<h1>X</h1>`, "aramplayer"},
+		{"no track list", `<div id="aramplayer"></div>`, "ul element"},
+		{"no tracks", `<div id="aramplayer"><ul></ul></div>`, "li elements"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := page.Parse(fixtureBase+"/item/x/", []byte(tt.body))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			_, err = snapshotItem(context.Background(), doc, doc.URL, selectors)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("snapshotItem() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"song-sc/internal/cache"
+	"song-sc/internal/crawlstate"
+	"song-sc/internal/health"
+	"song-sc/internal/logging"
+	"song-sc/internal/manifest"
+	"song-sc/internal/metrics"
+	"song-sc/internal/model"
+	"song-sc/internal/page"
+	"song-sc/internal/profile"
+	"song-sc/internal/queue"
+	"song-sc/internal/store"
+)
+
+// worker scrapes items with its own fetcher, so every worker drives a separate browser
+// session. Workers share the delivery channel and acknowledge their own deliveries.
+type worker struct {
+	id        int
+	conn      *queue.Conn
+	fetcher   page.Fetcher
+	selectors *profile.Profile
+	entities  *cache.Cache
+	sink      store.Sink
+	state     *crawlstate.State
+	full      bool
+	manifests *manifest.Runs
+	health    *health.Health
+	baseURL   string
+}
+
+// run handles deliveries until stop is done. Items are processed with work, which outlives
+// stop by the shutdown grace period, so the item in flight can still finish. Deliveries
+// received after stop are requeued without being started.
+func (w *worker) run(stop, work context.Context, deliveries <-chan amqp.Delivery) {
+	for {
+		select {
+		case <-stop.Done():
+			return
+		case msg, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			// select picks at random when stop is done and a delivery is ready as well
+			if stop.Err() != nil {
+				w.settle(work, msg.Nack(false, true), metrics.DeliveryNacked)
+				return
+			}
+			w.handle(work, msg)
+		}
+	}
+}
+
+// handle processes a single delivery. Everything logged on its behalf carries the
+// correlation fields of the message, taken from the envelope discovery published.
+func (w *worker) handle(ctx context.Context, msg amqp.Delivery) {
+	metrics.DeliveriesConsumed.Inc()
+	ctx = logging.With(ctx, logging.KeyWorker, w.id, logging.KeyMessageID, msg.MessageId)
+	envelope, err := queue.Items.Decode(msg)
+	if err != nil {
+		w.deadLetter(ctx, msg, err)
+		return
+	}
+	if !envelope.ProducedAt.IsZero() {
+		metrics.QueueLag.Observe(time.Since(envelope.ProducedAt).Seconds())
+	}
+	message := envelope.Payload
+	ctx = logging.With(ctx,
+		logging.KeyRunID, envelope.RunID,
+		logging.KeyMood, message.Mood,
+		logging.KeyPage, envelope.Page,
+		logging.KeyPageURL, envelope.SourceURL,
+		logging.KeyItemURL, message.Item.ItemURL,
+	)
+	logger := logging.From(ctx)
+	logger.Info("Received message")
+
+	if message.Mood == "" || message.Item.ItemURL == "" {
+		w.deadLetter(ctx, msg, errors.New("message has no mood or item url"))
+		return
+	}
+
+	if err := w.processItem(ctx, envelope.RunID, message.Mood, message.Item); err != nil {
+		if ctx.Err() != nil {
+			// interrupted by the shutdown, not the item's fault, it is picked up again later
+			logger.Warn("Shutdown interrupted the item, requeueing it", "error", err)
+			w.settle(ctx, msg.Nack(false, true), metrics.DeliveryNacked)
+			return
+		}
+		run := w.manifests.Get(envelope.RunID)
+		run.AddError(message.Mood, message.Item.ItemURL, err)
+		run.Update(message.Mood, func(stats *manifest.MoodStats) { stats.Failed++ })
+		metrics.DetailItems.WithLabelValues(message.Mood, metrics.ItemFailed).Inc()
+		logger.Error("Failed to process item, retrying", "attempt", queue.Attempts(msg)+1, "error", err)
+		if retryErr := queue.Items.Retry(ctx, w.conn, msg, err); retryErr != nil {
+			logger.Error("could not schedule retry", "error", retryErr)
+			w.settle(ctx, msg.Nack(false, true), metrics.DeliveryNacked)
+			return
+		}
+		w.settle(ctx, nil, metrics.DeliveryRetried)
+		return
+	}
+	logger.Info("Processed item")
+	w.health.ItemDone()
+	w.settle(ctx, msg.Ack(false), metrics.DeliveryAcked)
+}
+
+// deadLetter parks a message that can never be processed in the dead-letter queue.
+func (w *worker) deadLetter(ctx context.Context, msg amqp.Delivery, err error) {
+	logger := logging.From(ctx)
+	logger.Error("dead-lettering invalid message", "error", err)
+	if dlErr := queue.Items.DeadLetter(ctx, w.conn, msg, err); dlErr != nil {
+		logger.Error("could not dead-letter message", "error", dlErr)
+		w.settle(ctx, msg.Nack(false, true), metrics.DeliveryNacked)
+		return
+	}
+	w.settle(ctx, nil, metrics.DeliveryDeadLettered)
+}
+
+// settle counts a delivery settled with outcome unless settling it failed, the broker then
+// redelivers it once the channel is recovered.
+func (w *worker) settle(ctx context.Context, err error, outcome string) {
+	if err != nil {
+		logging.From(ctx).Warn("could not settle delivery", "outcome", outcome, "error", err)
+		return
+	}
+	metrics.DeliveriesSettled.WithLabelValues(outcome).Inc()
+}
+
+// processItem snapshots the item page, fetches the linked taxonomy pages it refers to and
+// saves the item to the sink. Items whose snapshot did not change since they were last
+// saved are skipped unless the worker runs a full crawl.
+func (w *worker) processItem(ctx context.Context, run, mood string, item model.Item) error {
+	selectors := w.selectors
+	logger := logging.From(ctx)
+	runManifest := w.manifests.Get(run)
+
+	itemDoc, err := w.fetcher.Fetch(ctx, rebase(item.ItemURL, w.baseURL))
+	if err != nil {
+		return fmt.Errorf("failed to go to the item url %s: %w", item.ItemURL, err)
+	}
+	snapshot, err := snapshotItem(ctx, itemDoc, item.ItemURL, selectors)
+	metrics.Extracted("snapshotItem", err)
+	if err != nil {
+		return fmt.Errorf("failed to read item %s: %w", item.Name, err)
+	}
+	key := crawlstate.Key(mood, item.ItemURL)
+	// the listing card supplies the name, type and image that are stored, a changed card
+	// has to be saved again even when the item page is the same
+	hash := crawlstate.Hash(struct {
+		Item     model.Item
+		Snapshot *itemSnapshot
+	}{item, snapshot})
+	// the card is recorded as the source of the state, discovery enqueues cards again until
+	// detail has recorded them
+	card := crawlstate.Hash(item)
+	seen := manifest.Item{Mood: mood, URL: item.ItemURL, Name: item.Name, Type: item.Type, Artist: item.ArtistName, Genre: item.Genre, Hash: hash}
+	if !w.full && !w.state.Changed(key, hash) {
+		logger.Info("Item is unchanged since the last run, skipping it")
+		runManifest.AddItem(seen)
+		runManifest.Update(mood, func(stats *manifest.MoodStats) { stats.Items++; stats.Unchanged++ })
+		metrics.DetailItems.WithLabelValues(mood, metrics.ItemUnchanged).Inc()
+		return w.state.RecordFrom(key, hash, card)
+	}
+
+	// the item is saved without the taxonomy that failed, but its state is not recorded so
+	// the next run fetches it again
+	complete := true
+	artists, err := GetAndSaveArtists(ctx, run, w.fetcher, snapshot.Artists, selectors, w.entities, w.sink)
+	metrics.Extracted("GetAndSaveArtists", err)
+	if err != nil {
+		logger.Warn("failed to save artists", "error", err)
+		complete = false
+	}
+	genres, err := GetAndSaveGenre(run, snapshot.Genres, w.entities, w.sink)
+	metrics.Extracted("GetAndSaveGenre", err)
+	if err != nil {
+		logger.Warn("failed to save genres", "error", err)
+		complete = false
+	}
+	moods, err := GetAndSaveMood(run, snapshot.Moods, w.entities, w.sink)
+	metrics.Extracted("GetAndSaveMood", err)
+	if err != nil {
+		logger.Warn("failed to save mood data", "error", err)
+		complete = false
+	}
+	pub, err := GetAndSavePublisher(run, snapshot.Publisher, w.entities, w.sink)
+	metrics.Extracted("GetAndSavePublisher", err)
+	if err != nil {
+		logger.Warn("failed to save publisher", "error", err)
+		complete = false
+	}
+	instruments, err := GetAndSaveInstrument(ctx, run, w.fetcher, snapshot.Instruments, selectors, w.entities, w.sink)
+	metrics.Extracted("GetAndSaveInstrument", err)
+	if err != nil {
+		logger.Warn("failed to save instruments", "error", err)
+		complete = false
+	}
+
+	catalogItem := store.Item{
+		ID:            snapshot.ID,
+		URL:           item.ItemURL,
+		Mood:          mood,
+		Name:          item.Name,
+		Type:          item.Type,
+		Image:         item.ImageURL,
+		Album:         item.Type == "آلبوم",
+		Publisher:     pub.NameEN,
+		PublisherID:   pub.ID,
+		Artists:       linkNames(artists),
+		ArtistIDs:     linkIDs(artists),
+		Genres:        linkNames(genres),
+		GenreIDs:      linkIDs(genres),
+		Moods:         linkNames(moods),
+		MoodIDs:       linkIDs(moods),
+		Instruments:   linkNames(instruments),
+		InstrumentIDs: linkIDs(instruments),
+	}
+	for _, t := range snapshot.Tracks {
+		catalogItem.Tracks = append(catalogItem.Tracks, model.Track{
+			ID:       t.ID,
+			ItemID:   snapshot.ID,
+			Title:    t.Title,
+			Artist:   t.Artist,
+			Album:    t.Album,
+			Info:     t.Info,
+			Image:    t.Image,
+			Duration: t.Duration,
+			MP3Link:  t.MP3Link,
+		})
+	}
+	if err := w.sink.SaveItem(ctx, catalogItem); err != nil {
+		return err
+	}
+	runManifest.AddItem(seen)
+	runManifest.Update(mood, func(stats *manifest.MoodStats) { stats.Items++; stats.Processed++ })
+	metrics.DetailItems.WithLabelValues(mood, metrics.ItemProcessed).Inc()
+	if !complete {
+		logger.Warn("Item was saved without some of its taxonomy, it is crawled again next run")
+		return nil
+	}
+	return w.state.RecordFrom(key, hash, card)
+}
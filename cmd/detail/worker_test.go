@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"song-sc/internal/cache"
+	"song-sc/internal/crawlstate"
+	"song-sc/internal/fakesite"
+	"song-sc/internal/manifest"
+	"song-sc/internal/model"
+	"song-sc/internal/page"
+	"song-sc/internal/store"
+)
+
+func newTestWorker(t *testing.T, dir string, full bool) *worker {
+	t.Helper()
+	sink, err := store.NewDir(filepath.Join(dir, "sink"))
+	if err != nil {
+		t.Fatalf("store.NewDir() error = %v", err)
+	}
+	entities, err := cache.Open(filepath.Join(dir, "cache.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("cache.Open() error = %v", err)
+	}
+	state, err := crawlstate.Open(filepath.Join(dir, "state.jsonl"))
+	if err != nil {
+		t.Fatalf("crawlstate.Open() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = sink.Close()
+		_ = entities.Close()
+		_ = state.Close()
+	})
+	return &worker{
+		fetcher:   page.NewHTTPFetcher(),
+		selectors: loadProfile(t),
+		entities:  entities,
+		sink:      sink,
+		state:     state,
+		full:      full,
+		manifests: manifest.NewRuns(dir, "detail", full),
+	}
+}
+
+func TestProcessItemFakesite(t *testing.T) {
+	catalog := fakesite.NewCatalog(fakesite.Options{Moods: 1, ItemsPerMood: 4, PageSize: 4})
+	server := httptest.NewServer(fakesite.NewServer(catalog))
+	defer server.Close()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	mood := catalog.Moods[0]
+	var items []model.Item
+	for _, item := range mood.Items {
+		items = append(items, model.Item{
+			Name:     item.Name,
+			Type:     item.Type,
+			ItemURL:  server.URL + "/item/" + item.Slug + "/",
+			ImageURL: server.URL + "/img/" + item.Slug + ".jpg",
+		})
+	}
+
+	w := newTestWorker(t, dir, false)
+	for _, item := range items {
+		if err := w.processItem(ctx, "run-1", mood.Name, item); err != nil {
+			t.Fatalf("processItem(%s) error = %v", item.Name, err)
+		}
+	}
+	if stats := w.manifests.Get("run-1").Moods[mood.Name]; stats.Processed != len(items) || stats.Unchanged != 0 {
+		t.Errorf("run-1 stats = %+v, want %d processed", stats, len(items))
+	}
+	for _, item := range items {
+		if !w.state.Done(crawlstate.Key(mood.Name, item.ItemURL), crawlstate.Hash(item)) {
+			t.Errorf("the card of %s is not recorded as processed", item.Name)
+		}
+	}
+
+	saved, err := store.LoadCatalog(store.Config{Kind: store.KindDir, Path: filepath.Join(dir, "sink")})
+	if err != nil {
+		t.Fatalf("LoadCatalog() error = %v", err)
+	}
+	albums, tracks := 0, 0
+	for _, item := range mood.Items {
+		if item.Type == fakesite.TypeAlbum {
+			albums++
+		}
+		tracks += len(item.Tracks)
+	}
+	counts := []struct {
+		name      string
+		got, want int
+	}{
+		{"albums", len(saved.Albums), albums},
+		{"tracks", len(saved.Tracks), tracks},
+		{"artists", len(saved.Artists), 4},
+		{"instruments", len(saved.Instruments), 3},
+		{"genres", len(saved.Genres), 3},
+		{"moods", len(saved.Moods), 1},
+		{"publishers", len(saved.Publishers), 2},
+	}
+	for _, c := range counts {
+		if c.got != c.want {
+			t.Errorf("saved %d %s, want %d", c.got, c.name, c.want)
+		}
+	}
+	artistID := model.EntityID(model.KindArtist, server.URL+"/artist/artist-1/")
+	if artist, ok := saved.Artists[artistID]; !ok || artist.NameEN != "Artist 1" {
+		t.Errorf("artist %s = %+v, want Artist 1", artistID, artist)
+	}
+
+	// the same items again are unchanged, a full crawl processes them anyway
+	for _, item := range items {
+		if err := w.processItem(ctx, "run-2", mood.Name, item); err != nil {
+			t.Fatalf("processItem(%s) error = %v", item.Name, err)
+		}
+	}
+	if stats := w.manifests.Get("run-2").Moods[mood.Name]; stats.Unchanged != len(items) || stats.Processed != 0 {
+		t.Errorf("run-2 stats = %+v, want %d unchanged", stats, len(items))
+	}
+	w.full = true
+	if err := w.processItem(ctx, "run-3", mood.Name, items[0]); err != nil {
+		t.Fatalf("processItem() error = %v", err)
+	}
+	if stats := w.manifests.Get("run-3").Moods[mood.Name]; stats.Processed != 1 {
+		t.Errorf("run-3 stats = %+v, want 1 processed", stats)
+	}
+
+	// a renamed card is saved again even though the item page did not change
+	w.full = false
+	renamed := items[1]
+	renamed.Name = "Renamed"
+	if err := w.processItem(ctx, "run-4", mood.Name, renamed); err != nil {
+		t.Fatalf("processItem() error = %v", err)
+	}
+	if stats := w.manifests.Get("run-4").Moods[mood.Name]; stats.Processed != 1 {
+		t.Errorf("run-4 stats = %+v, want 1 processed", stats)
+	}
+}
+
+func TestProcessItemMissingPage(t *testing.T) {
+	server := httptest.NewServer(fakesite.NewServer(fakesite.NewCatalog(fakesite.DefaultOptions)))
+	defer server.Close()
+
+	w := newTestWorker(t, t.TempDir(), false)
+	item := model.Item{Name: "Gone", ItemURL: server.URL + "/item/gone/"}
+	if err := w.processItem(context.Background(), "run-1", "Mood 1", item); err == nil {
+		t.Fatal("processItem() of a missing page succeeded")
+	}
+}
+
+// acknowledger records how deliveries were settled.
+type acknowledger struct {
+	acked, requeued int
+}
+
+func (a *acknowledger) Ack(tag uint64, multiple bool) error { a.acked++; return nil }
+func (a *acknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	if requeue {
+		a.requeued++
+	}
+	return nil
+}
+func (a *acknowledger) Reject(tag uint64, requeue bool) error { return a.Nack(tag, false, requeue) }
+
+func TestRunAfterStop(t *testing.T) {
+	stop, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := &worker{}
+	// select picks a ready case at random, a few rounds take both of them
+	for range 50 {
+		ack := &acknowledger{}
+		deliveries := make(chan amqp.Delivery, 1)
+		deliveries <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 1}
+		w.run(stop, context.Background(), deliveries)
+		if len(deliveries) == 0 && ack.requeued != 1 || ack.acked != 0 {
+			t.Fatalf("delivery left %d in the channel, acked %d, requeued %d, want it untouched or requeued", len(deliveries), ack.acked, ack.requeued)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Command diff reports what changed between two crawl runs, given either their manifests
+// or the catalogs they wrote.
+//
+//	diff [-json] OLD.json NEW.json
+//	diff [-json] OLD_CATALOG NEW_CATALOG
+//
+// A catalog is the directory written by the fs sink, the directory of a jsonl export or an
+// SQLite database file. Like diff(1), it exits with status 1 when the runs differ and 2
+// when they could not be compared.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"song-sc/internal/diff"
+	"song-sc/internal/manifest"
+	"song-sc/internal/store"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run compares the runs named by args and returns the exit status: 0 when they match, 1
+// when they differ and 2 when they could not be compared.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "write the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: diff [-json] OLD NEW")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	from, to := flags.Arg(0), flags.Arg(1)
+
+	report, err := compare(from, to)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if *asJSON {
+		err = report.WriteJSON(stdout)
+	} else {
+		err = report.WriteText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if !report.Empty() {
+		return 1
+	}
+	return 0
+}
+
+// compare diffs two manifests when both paths end in .json, two catalogs otherwise.
+func compare(from, to string) (diff.Report, error) {
+	if strings.HasSuffix(from, ".json") && strings.HasSuffix(to, ".json") {
+		a, err := manifest.Read(from)
+		if err != nil {
+			return diff.Report{}, err
+		}
+		b, err := manifest.Read(to)
+		if err != nil {
+			return diff.Report{}, err
+		}
+		return diff.Manifests(a, b), nil
+	}
+	a, err := loadCatalog(from)
+	if err != nil {
+		return diff.Report{}, err
+	}
+	b, err := loadCatalog(to)
+	if err != nil {
+		return diff.Report{}, err
+	}
+	return diff.Catalogs(from, to, a, b), nil
+}
+
+func loadCatalog(path string) (*store.Catalog, error) {
+	cfg, err := store.DetectConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog %s: %w", path, err)
+	}
+	return store.LoadCatalog(cfg)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"song-sc/internal/manifest"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	write := func(runID string, items ...manifest.Item) string {
+		m := manifest.New(runID, "discover", false)
+		for _, item := range items {
+			m.AddItem(item)
+		}
+		path, err := m.Write(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	item := manifest.Item{Mood: "m", URL: "https://songsara.net/item/a/", Name: "A", Hash: "1"}
+	old := write("old", item)
+	same := write("same", item)
+	changed := write("changed", manifest.Item{Mood: "m", URL: item.URL, Name: "A", Hash: "2"})
+
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+		output string
+	}{
+		{name: "same", args: []string{old, same}, status: 0, output: "diff old -> same"},
+		{name: "changed", args: []string{old, changed}, status: 1, output: "albums: 0 added, 0 removed, 0 changed\n"},
+		{name: "json", args: []string{"-json", old, changed}, status: 1, output: `"name": "items"`},
+		{name: "one argument", args: []string{old}, status: 2},
+		{name: "unknown flag", args: []string{"-x", old, same}, status: 2},
+		{name: "missing manifest", args: []string{old, filepath.Join(dir, "missing.json")}, status: 2},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if status := run(tt.args, &stdout, &stderr); status != tt.status {
+			t.Errorf("%s: status %d, want %d, stderr %q", tt.name, status, tt.status, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), tt.output) {
+			t.Errorf("%s: output %q does not contain %q", tt.name, stdout.String(), tt.output)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"song-sc/internal/model"
+	"song-sc/internal/page"
+	"song-sc/internal/profile"
+)
+
+// Reasons a field of an item card cannot be read, a *CardError matches its reason with
+// errors.Is.
+var (
+	ErrMissingElement  = errors.New("missing element")
+	ErrIndexOutOfRange = errors.New("index out of range")
+	// ErrEmptyAttribute is an attribute or text that is missing or empty.
+	ErrEmptyAttribute = errors.New("empty attribute")
+)
+
+// CardError is a field of an item card that could not be read.
+type CardError struct {
+	Field  string
+	Reason error
+	Err    error
+}
+
+func (e *CardError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("card %s: %v", e.Field, e.Reason)
+	}
+	return fmt.Sprintf("card %s: %v: %v", e.Field, e.Reason, e.Err)
+}
+
+func (e *CardError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Reason}
+	}
+	return []error{e.Reason, e.Err}
+}
+
+// extractCard reads an item card of a listing page. The link and the type are required, a
+// card without them cannot be handed to detail and is returned with an error. Other fields
+// that cannot be read are left empty and reported as warnings.
+func extractCard(card *page.Element, selectors *profile.Profile) (item model.Item, warnings []error, err error) {
+	listing := selectors.Listing
+
+	item.ImageURL, err = cardAttribute(card, "image", listing.Image, selectors.Attributes.Image)
+	if err != nil {
+		warnings = append(warnings, err)
+	}
+
+	var required []error
+	item.ItemURL, err = cardAttribute(card, "link", listing.Link, selectors.Attributes.Link)
+	if err != nil {
+		required = append(required, err)
+	}
+	item.Type, err = cardType(card, listing)
+	if err != nil {
+		required = append(required, err)
+	}
+
+	details, err := listing.Details.Find(card)
+	if err != nil {
+		warnings = append(warnings, &CardError{Field: "details", Reason: ErrMissingElement, Err: err})
+		return item, warnings, errors.Join(required...)
+	}
+	fields, err := listing.Detail.FindAll(details)
+	if err != nil {
+		warnings = append(warnings, &CardError{Field: "details", Reason: ErrMissingElement, Err: err})
+		return item, warnings, errors.Join(required...)
+	}
+	for i, field := range listing.DetailFields {
+		if i >= len(fields) {
+			warnings = append(warnings, &CardError{
+				Field:  field,
+				Reason: ErrIndexOutOfRange,
+				Err:    fmt.Errorf("detail %d of %d", i, len(fields)),
+			})
+			continue
+		}
+		text, _ := fields[i].Text()
+		switch field {
+		case "name":
+			item.Name = text
+		case "artist_name":
+			item.ArtistName = text
+		case "genre":
+			item.Genre = text
+		case "date":
+			item.Date = text
+		}
+	}
+	return item, warnings, errors.Join(required...)
+}
+
+// cardAttribute reads attribute of the element sel finds in card.
+func cardAttribute(card *page.Element, field string, sel profile.Selector, attribute string) (string, error) {
+	element, err := sel.Find(card)
+	if err != nil {
+		return "", &CardError{Field: field, Reason: ErrMissingElement, Err: err}
+	}
+	value, err := element.GetAttribute(attribute)
+	if err != nil {
+		return "", &CardError{Field: field, Reason: ErrEmptyAttribute, Err: err}
+	}
+	// an empty href or src resolves to the listing page itself
+	if strings.TrimSpace(value) == "" || value == card.PageURL() {
+		return "", &CardError{Field: field, Reason: ErrEmptyAttribute, Err: fmt.Errorf("%s is empty", attribute)}
+	}
+	return value, nil
+}
+
+// cardType reads the type from the label at TypeLabelIndex among the labels of card.
+func cardType(card *page.Element, listing profile.ListingSelectors) (string, error) {
+	labels, err := listing.Labels.FindAll(card)
+	if err != nil {
+		return "", &CardError{Field: "type", Reason: ErrMissingElement, Err: err}
+	}
+	if listing.TypeLabelIndex >= len(labels) {
+		return "", &CardError{
+			Field:  "type",
+			Reason: ErrIndexOutOfRange,
+			Err:    fmt.Errorf("label %d of %d", listing.TypeLabelIndex, len(labels)),
+		}
+	}
+	element, err := listing.Type.Find(labels[listing.TypeLabelIndex])
+	if err != nil {
+		return "", &CardError{Field: "type", Reason: ErrMissingElement, Err: err}
+	}
+	text, _ := element.Text()
+	if text == "" {
+		return "", &CardError{Field: "type", Reason: ErrEmptyAttribute, Err: errors.New("text is empty")}
+	}
+	return text, nil
+}
+
+// cardMessages flattens errs, joined errors included, into their messages.
+func cardMessages(errs []error) []string {
+	var messages []string
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			if _, isCard := err.(*CardError); !isCard {
+				messages = append(messages, cardMessages(joined.Unwrap())...)
+				continue
+			}
+		}
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
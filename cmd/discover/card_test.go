@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"song-sc/internal/model"
+	"song-sc/internal/page"
+	"song-sc/internal/profile"
+)
+
+const fixtureBase = "http://127.0.0.1:18080"
+
+func loadProfile(t *testing.T) *profile.Profile {
+	t.Helper()
+	selectors, err := profile.Load("")
+	if err != nil {
+		t.Fatalf("profile.Load() error = %v", err)
+	}
+	return selectors
+}
+
+func listingCards(t *testing.T, doc *page.Document, selectors *profile.Profile) []*page.Element {
+	t.Helper()
+	container, err := selectors.Listing.Container.Find(doc.Element)
+	if err != nil {
+		t.Fatalf("Container.Find() error = %v", err)
+	}
+	cards, err := selectors.Listing.Card.FindAll(container)
+	if err != nil {
+		t.Fatalf("Card.FindAll() error = %v", err)
+	}
+	return cards
+}
+
+func TestExtractCardReplay(t *testing.T) {
+	selectors := loadProfile(t)
+	replayer := page.NewReplayer(page.Archive("../../testdata/fixtures"))
+	doc, err := replayer.Fetch(context.Background(), fixtureBase+"/moods/mood-1")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	want := []model.Item{
+		{Name: "Item 1", ArtistName: "Artist 1", Genre: "Genre 1", Date: "1403/01/01", Type: "آلبوم",
+			ItemURL: fixtureBase + "/item/item-1/", ImageURL: fixtureBase + "/img/item-1.jpg"},
+		{Name: "Item 2", ArtistName: "Artist 2", Genre: "Genre 2", Date: "1403/02/02", Type: "تک آهنگ",
+			ItemURL: fixtureBase + "/item/item-2/", ImageURL: fixtureBase + "/img/item-2.jpg"},
+		{Name: "Item 3", ArtistName: "Artist 3", Genre: "Genre 3", Date: "1403/03/03", Type: "آلبوم",
+			ItemURL: fixtureBase + "/item/item-3/", ImageURL: fixtureBase + "/img/item-3.jpg"},
+	}
+	cards := listingCards(t, doc, selectors)
+	if len(cards) != len(want) {
+		t.Fatalf("found %d cards, want %d", len(cards), len(want))
+	}
+	for i, card := range cards {
+		item, warnings, err := extractCard(card, selectors)
+		if err != nil || len(warnings) > 0 {
+			t.Errorf("card %d: warnings = %v, error = %v", i, warnings, err)
+		}
+		if item != want[i] {
+			t.Errorf("card %d = %+v, want %+v", i, item, want[i])
+		}
+	}
+}
+
+const cardTemplate = `<div class="box-i"><div class="posting col-6 col-sm-4 col-md-3 col-lg-2 col-xl-2">%s</div></div>`
+
+func TestExtractCard(t *testing.T) {
+	selectors := loadProfile(t)
+	const (
+		link   = `<a href="/item/x/"><img src="/img/x.jpg"></a>`
+		labels = `<div class="TSale-txt"><span>1403/01/01</span></div><div class="TSale-txt"><span>آلبوم</span></div>`
+		fields = `<section><ul><li>X</li><li>Artist</li><li>Genre</li><li>1403/01/01</li></ul></section>`
+	)
+	tests := []struct {
+		name     string
+		card     string
+		required []string // fields failing with an error
+		warnings []string // fields reported as warnings
+		reason   error
+	}{
+		{"complete", link + labels + fields, nil, nil, nil},
+		{"no link", `<img src="/img/x.jpg">` + labels + fields, []string{"link"}, nil, ErrMissingElement},
+		{"no image", `<a href="/item/x/"></a>` + labels + fields, nil, []string{"image"}, ErrMissingElement},
+		{"empty href", `<a href=""><img src="/img/x.jpg"></a>` + labels + fields, []string{"link"}, nil, ErrEmptyAttribute},
+		{"one label", link + `<div class="TSale-txt"><span>1403/01/01</span></div>` + fields, []string{"type"}, nil, ErrIndexOutOfRange},
+		{"empty type", link + `<div class="TSale-txt"><span>1</span></div><div class="TSale-txt"><span></span></div>` + fields, []string{"type"}, nil, ErrEmptyAttribute},
+		{"no details", link + labels, nil, []string{"details"}, ErrMissingElement},
+		{"short details", link + labels + `<section><ul><li>X</li><li>Artist</li></ul></section>`, nil, []string{"genre", "date"}, ErrIndexOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := page.Parse("https://songsara.net/moods/x/", []byte(strings.Replace(cardTemplate, "%s", tt.card, 1)))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			cards := listingCards(t, doc, selectors)
+			if len(cards) != 1 {
+				t.Fatalf("found %d cards, want 1", len(cards))
+			}
+			_, warnings, err := extractCard(cards[0], selectors)
+
+			if got := cardFields(t, err); !equal(got, tt.required) {
+				t.Errorf("required fields = %v, want %v (error %v)", got, tt.required, err)
+			}
+			if got := cardFields(t, errors.Join(warnings...)); !equal(got, tt.warnings) {
+				t.Errorf("warning fields = %v, want %v", got, tt.warnings)
+			}
+			if tt.reason != nil && !errors.Is(errors.Join(append(warnings, err)...), tt.reason) {
+				t.Errorf("errors %v, %v do not match %v", err, warnings, tt.reason)
+			}
+		})
+	}
+}
+
+// cardFields returns the fields of the card errors joined in err.
+func cardFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var fields []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var cardErr *CardError
+		if !errors.As(err, &cardErr) {
+			t.Fatalf("%v is not a *CardError", err)
+		}
+		fields = append(fields, cardErr.Field)
+	}
+	return fields
+}
+
+func equal(a, b []string) bool {
+	return strings.Join(a, ",") == strings.Join(b, ",")
+}
+
+func TestCardMessages(t *testing.T) {
+	link := &CardError{Field: "link", Reason: ErrMissingElement}
+	typ := &CardError{Field: "type", Reason: ErrEmptyAttribute, Err: errors.New("text is empty")}
+	image := &CardError{Field: "image", Reason: ErrEmptyAttribute}
+
+	got := cardMessages([]error{image, errors.Join(link, typ), nil})
+	want := []string{
+		"card image: empty attribute",
+		"card link: missing element",
+		"card type: empty attribute: text is empty",
+	}
+	if !equal(got, want) {
+		t.Errorf("cardMessages() = %q, want %q", got, want)
+	}
+	if got := cardMessages([]error{nil}); got != nil {
+		t.Errorf("cardMessages(nil) = %q, want none", got)
+	}
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"song-sc/internal/crawlstate"
+	"song-sc/internal/health"
+	"song-sc/internal/logging"
+	"song-sc/internal/manifest"
+	"song-sc/internal/metrics"
+	"song-sc/internal/model"
+	"song-sc/internal/page"
+	"song-sc/internal/profile"
+	"song-sc/internal/queue"
+	"song-sc/internal/shutdown"
+)
+
+type MoodInfo struct {
+	Name string
+	Link string
+	Page int
+	// firstPage is set on the first page of a mood, the fetched page is handed back on it so
+	// pagination reads the page count without fetching the page again.
+	firstPage chan<- *page.Document
+}
+
+const (
+	defaultBaseURL = "https://songsara.net"
+	moodsPath      = "/moods"
+
+	defaultOutboxPath       = "outbox.jsonl"
+	defaultListingStatePath = "listing-state.jsonl"
+	defaultItemStatePath    = "item-state.jsonl"
+	defaultManifestDir      = "manifests"
+	defaultMetricsAddr      = ":9101"
+)
+
+func main() {
+	os.Exit(discover())
+}
+
+// discover enqueues the items of every listing page and returns the exit status. A shutdown
+// signal stops it before the next item card, the manifest of the partial run is still
+// written and marked interrupted.
+func discover() int {
+	full := flag.Bool("full", false, "enqueue every item, not only new and changed ones")
+	flag.Parse()
+	if err := logging.Setup("discover"); err != nil {
+		log.Fatal(err)
+	}
+
+	rabbitUrl := os.Getenv("RABBITMQ_URL")
+	baseURL := os.Getenv("SONGSARA_BASE_URL")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	selectors, err := profile.Load(os.Getenv("SELECTOR_PROFILE"))
+	if err != nil {
+		logging.Fatal("could not load the selector profile", "error", err)
+	}
+
+	listingStatePath := os.Getenv("LISTING_STATE_PATH")
+	if listingStatePath == "" {
+		listingStatePath = defaultListingStatePath
+	}
+	listing, err := crawlstate.Open(listingStatePath)
+	if err != nil {
+		logging.Fatal("could not open the listing state", "error", err)
+	}
+	defer listing.Close()
+
+	// detail records the cards it processed, a card it never finished is enqueued again
+	itemStatePath := os.Getenv("ITEM_STATE_PATH")
+	if itemStatePath == "" {
+		itemStatePath = defaultItemStatePath
+	}
+	processed, err := crawlstate.Read(itemStatePath)
+	if err != nil {
+		logging.Fatal("could not read the item state", "error", err)
+	}
+
+	runID := model.NewID()
+	// every line of the run carries its ID, detail logs it for each item as well
+	slog.SetDefault(slog.Default().With(logging.KeyRunID, runID))
+	slog.Info("Starting crawl run", "full", *full)
+	run := manifest.New(runID, "discover", *full)
+	manifestDir := os.Getenv("MANIFEST_DIR")
+	if manifestDir == "" {
+		manifestDir = defaultManifestDir
+	}
+
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = defaultMetricsAddr
+	}
+	checks := health.New()
+	checks.Ready("storage", health.Writable(filepath.Dir(listingStatePath)))
+	mux := http.NewServeMux()
+	checks.Register(mux)
+	metrics.Serve(metricsAddr, mux)
+
+	ctx, exitCode := shutdown.Notify(context.Background())
+	go func() {
+		<-ctx.Done()
+		checks.Stopping()
+	}()
+
+	conn, err := queue.Dial(ctx, rabbitUrl)
+	if err != nil {
+		logging.Fatal("could not connect to RabbitMQ", "error", err)
+	}
+	defer conn.Close()
+	checks.Ready("broker", conn.Ping)
+
+	outboxPath := os.Getenv("OUTBOX_PATH")
+	if outboxPath == "" {
+		outboxPath = defaultOutboxPath
+	}
+	outbox := queue.NewOutbox(outboxPath)
+	flushed, err := outbox.Flush(ctx, conn, queue.Items)
+	if err != nil {
+		slog.Warn("could not flush the outbox", "error", err)
+	} else if flushed > 0 {
+		slog.Info("Published messages left over in the outbox", "messages", flushed)
+	}
+	conn.UseOutbox(outbox)
+	fetcherConfig := page.ConfigFromEnv()
+	fetcher, err := page.Open(fetcherConfig)
+	if err != nil {
+		logging.Fatal("could not open the fetcher", "error", err)
+	}
+	defer fetcher.Close()
+	checks.Live("fetcher", fetcher.Ping)
+	// pagination is probed over plain http since a browser does not expose the status code
+	proberConfig := fetcherConfig
+	proberConfig.Kind = "http"
+	prober, err := page.Open(proberConfig)
+	if err != nil {
+		logging.Fatal("could not open the pagination prober", "error", err)
+	}
+	defer prober.Close()
+	checks.Started()
+
+	doc, err := fetcher.Fetch(ctx, strings.TrimSuffix(baseURL, "/")+moodsPath)
+	if err != nil {
+		logging.Fatal("could not open the moods page", "error", err)
+	}
+
+	selectElement, err := selectors.Moods.Container.Find(doc.Element)
+	metrics.Extracted("moods", err)
+	if err != nil {
+		logging.Fatal("could not find the moods container", "error", err)
+	}
+
+	moods, err := selectors.Moods.Link.FindAll(selectElement)
+	if err != nil {
+		logging.Fatal("could not find mood links", "error", err)
+	}
+	if len(moods) == 0 {
+		logging.Fatal("could not find any mood", "error", selectors.Moods.Link.Mismatch(selectElement))
+	}
+
+	var moodInfos []MoodInfo
+	for _, moodElement := range moods {
+		moodNameElement, err := selectors.Moods.Name.Find(moodElement)
+		if err != nil {
+			slog.Warn("could not find the name of a mood", "error", err)
+			continue
+		}
+		moodNameText, err := moodNameElement.Text()
+		if err != nil {
+			slog.Warn("could not read the name of a mood", "error", err)
+			continue
+		}
+		moodLink, err := moodElement.GetAttribute(selectors.Attributes.Link)
+		if err != nil {
+			slog.Warn("could not read the link of a mood", logging.KeyMood, moodNameText, "error", err)
+			continue
+		}
+		moodInfos = append(moodInfos, MoodInfo{Name: moodNameText, Link: moodLink, Page: 1})
+	}
+	metrics.MoodsDiscovered.Add(float64(len(moodInfos)))
+
+	paginator := &paginator{prober: prober, selectors: selectors}
+	pages := make(chan MoodInfo)
+	go func() {
+		defer close(pages)
+		send := func(moodInfo MoodInfo) {
+			select {
+			case pages <- moodInfo:
+			case <-ctx.Done():
+			}
+		}
+		for _, moodInfo := range moodInfos {
+			if ctx.Err() != nil {
+				return
+			}
+			first := make(chan *page.Document, 1)
+			moodInfo.firstPage = first
+			send(moodInfo)
+			var firstDoc *page.Document
+			select {
+			case firstDoc = <-first:
+			case <-ctx.Done():
+				return
+			}
+			moodCtx := logging.With(ctx, logging.KeyMood, moodInfo.Name)
+			paginator.discover(moodCtx, moodInfo.Link, firstDoc, func(n int) {
+				paginatedURL := pageURL(moodInfo.Link, n)
+				logging.From(moodCtx).Debug("Found listing page", logging.KeyPage, n, logging.KeyPageURL, paginatedURL)
+				send(MoodInfo{Name: moodInfo.Name, Link: paginatedURL, Page: n})
+			})
+		}
+	}()
+
+	var published, unchanged int
+	for moodInfo := range pages {
+		if ctx.Err() != nil {
+			break
+		}
+		pageCtx := logging.With(ctx, logging.KeyMood, moodInfo.Name, logging.KeyPage, moodInfo.Page, logging.KeyPageURL, moodInfo.Link)
+		logger := logging.From(pageCtx)
+		logger.Info("Processing listing page")
+
+		moodDoc, err := fetcher.Fetch(pageCtx, moodInfo.Link)
+		if moodInfo.firstPage != nil {
+			moodInfo.firstPage <- moodDoc
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			logger.Error("could not open the listing page", "error", err)
+			run.AddError(moodInfo.Name, moodInfo.Link, err)
+			continue
+		}
+		run.Update(moodInfo.Name, func(stats *manifest.MoodStats) { stats.Pages++ })
+		metrics.PagesDiscovered.WithLabelValues(moodInfo.Name).Inc()
+
+		itemSelection, err := selectors.Listing.Container.Find(moodDoc.Element)
+		if err != nil {
+			metrics.Extracted("listing", err)
+			logger.Error("could not find the listing container", "error", err)
+			run.AddError(moodInfo.Name, moodInfo.Link, err)
+			continue
+		}
+
+		items, err := selectors.Listing.Card.FindAll(itemSelection)
+		if err != nil {
+			metrics.Extracted("listing", err)
+			logger.Error("could not find item cards", "error", err)
+			run.AddError(moodInfo.Name, moodInfo.Link, err)
+			continue
+		}
+		if len(items) == 0 {
+			err := selectors.Listing.Card.Mismatch(itemSelection)
+			metrics.Extracted("listing", err)
+			logger.Error("could not find item cards", "error", err)
+			run.AddError(moodInfo.Name, moodInfo.Link, err)
+			continue
+		}
+		metrics.Extracted("listing", nil)
+		summary := manifest.PageSummary{Mood: moodInfo.Name, Page: moodInfo.Page, URL: moodInfo.Link, Cards: len(items)}
+		for i, itemElement := range items {
+			// a publish under a cancelled ctx still reaches the broker but is parked in
+			// the outbox as well, so the rest of the page is left for the next run
+			if ctx.Err() != nil {
+				break
+			}
+			itemObj, warnings, err := extractCard(itemElement, selectors)
+			metrics.Extracted("card", err)
+			if err != nil || len(warnings) > 0 {
+				issue := manifest.CardIssue{
+					Mood:        moodInfo.Name,
+					Page:        moodInfo.Page,
+					PageURL:     moodInfo.Link,
+					Card:        i,
+					ItemURL:     itemObj.ItemURL,
+					Errors:      cardMessages(append(warnings, err)),
+					Quarantined: err != nil,
+				}
+				if err != nil {
+					issue.HTML = itemElement.HTML()
+				}
+				run.AddCardIssue(issue)
+			}
+			for _, warning := range warnings {
+				logger.Warn("could not read an item card completely", "card", i, "error", warning)
+			}
+			if err != nil {
+				summary.Quarantined++
+				logger.Error("quarantining an unreadable item card", "card", i, "error", err)
+				continue
+			}
+			if len(warnings) > 0 {
+				summary.Incomplete++
+			} else {
+				summary.Extracted++
+			}
+			messageID := model.ItemKey(itemObj.ItemURL)
+			itemLogger := logger.With(logging.KeyItemURL, itemObj.ItemURL, logging.KeyMessageID, messageID)
+			itemLogger.Debug("Found item", "name", itemObj.Name, "type", itemObj.Type, "artist", itemObj.ArtistName, "genre", itemObj.Genre)
+			// the card is all discovery sees of an item, an unchanged card means an unchanged item
+			key := crawlstate.Key(moodInfo.Name, itemObj.ItemURL)
+			hash := crawlstate.Hash(itemObj)
+			run.AddItem(manifest.Item{
+				Mood:   moodInfo.Name,
+				URL:    itemObj.ItemURL,
+				Name:   itemObj.Name,
+				Type:   itemObj.Type,
+				Artist: itemObj.ArtistName,
+				Genre:  itemObj.Genre,
+				Hash:   hash,
+			})
+			run.Update(moodInfo.Name, func(stats *manifest.MoodStats) { stats.Items++ })
+			if !*full && !listing.Changed(key, hash) && processed.Done(key, hash) {
+				unchanged++
+				metrics.DiscoveredItems.WithLabelValues(moodInfo.Name, metrics.ItemUnchanged).Inc()
+				run.Update(moodInfo.Name, func(stats *manifest.MoodStats) { stats.Unchanged++ })
+				checks.ItemDone()
+				if err := listing.Record(key, hash); err != nil {
+					itemLogger.Warn("could not record item", "error", err)
+				}
+				continue
+			}
+			err = queue.Items.Publish(ctx, conn, model.Envelope[model.ItemMessage]{
+				MessageID: messageID,
+				RunID:     runID,
+				SourceURL: moodInfo.Link,
+				Page:      moodInfo.Page,
+				Payload:   model.ItemMessage{Mood: moodInfo.Name, Item: itemObj},
+			})
+			if err != nil {
+				itemLogger.Error("could not publish item", "error", err)
+				run.AddError(moodInfo.Name, itemObj.ItemURL, err)
+				run.Update(moodInfo.Name, func(stats *manifest.MoodStats) { stats.Failed++ })
+				metrics.DiscoveredItems.WithLabelValues(moodInfo.Name, metrics.ItemFailed).Inc()
+				continue
+			}
+			published++
+			itemLogger.Info("Published item")
+			checks.ItemDone()
+			metrics.DiscoveredItems.WithLabelValues(moodInfo.Name, metrics.ItemProcessed).Inc()
+			run.Update(moodInfo.Name, func(stats *manifest.MoodStats) { stats.Processed++ })
+			if err := listing.Record(key, hash); err != nil {
+				itemLogger.Warn("could not record item", "error", err)
+			}
+		}
+		run.AddPage(summary)
+		logger.Info("Extracted item cards", "cards", summary.Cards, "extracted", summary.Extracted, "incomplete", summary.Incomplete, "quarantined", summary.Quarantined)
+	}
+	if ctx.Err() != nil {
+		slog.Warn("Discovery was interrupted")
+		run.Interrupted = true
+	}
+	slog.Info("Discovery finished", "published", published, "unchanged", unchanged)
+	path, err := run.Write(manifestDir)
+	if err != nil {
+		slog.Error("could not write the run manifest", "error", err)
+		return 1
+	}
+	slog.Info("Wrote the run manifest", "path", path)
+	return exitCode()
+}
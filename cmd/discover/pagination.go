@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"song-sc/internal/logging"
+	"song-sc/internal/page"
+	"song-sc/internal/profile"
+)
+
+const (
+	probeConcurrency = 4
+	// maxPages stops the exponential search on sites that answer every page with 200.
+	maxPages = 4096
+)
+
+var pageNumberPattern = regexp.MustCompile(`/page/(\d+)/?$`)
+
+// paginator finds the listing pages of a mood. It reads the page count from the pagination
+// links of the first page, which the caller already fetched, and falls back to probing page
+// URLs over HTTP.
+type paginator struct {
+	prober    page.Fetcher
+	selectors *profile.Profile
+}
+
+func pageURL(moodLink string, n int) string {
+	return strings.TrimSuffix(moodLink, "/") + "/page/" + strconv.Itoa(n) + "/"
+}
+
+// discover calls found for every page after the first one, in order, as soon as the page
+// is known to exist. first is the first page of the mood, nil when it could not be fetched.
+// It stops early once ctx is done.
+func (p *paginator) discover(ctx context.Context, moodLink string, first *page.Document, found func(n int)) {
+	if count := p.countFromLinks(first); count > 0 {
+		for n := 2; n <= count && ctx.Err() == nil; n++ {
+			found(n)
+		}
+		return
+	}
+
+	last := 1 // highest page known to exist
+	emitted := 1
+	emitUpTo := func(n int) {
+		for emitted < n && ctx.Err() == nil {
+			emitted++
+			found(emitted)
+		}
+	}
+
+	// exponential phase: probe 2, 4, 8, ... in concurrent batches until a page is missing
+	missing := 0
+	next := 2
+	for missing == 0 {
+		batch := make([]int, 0, probeConcurrency)
+		for len(batch) < probeConcurrency && next <= maxPages {
+			batch = append(batch, next)
+			next *= 2
+		}
+		if len(batch) == 0 {
+			logging.From(ctx).Warn("stopping pagination", "pages", maxPages)
+			missing = maxPages + 1
+			break
+		}
+		last, missing = p.probeInOrder(ctx, moodLink, batch, last)
+		emitUpTo(last)
+	}
+
+	// search phase: split the gap between the last existing and the first missing page
+	for missing-last > 1 {
+		step := max((missing-last)/(probeConcurrency+1), 1)
+		batch := make([]int, 0, probeConcurrency)
+		for n := last + step; n < missing && len(batch) < probeConcurrency; n += step {
+			batch = append(batch, n)
+		}
+		var firstMissing int
+		last, firstMissing = p.probeInOrder(ctx, moodLink, batch, last)
+		if firstMissing != 0 {
+			missing = firstMissing
+		}
+		emitUpTo(last)
+	}
+}
+
+// probeInOrder probes the ascending page numbers concurrently and returns the highest page
+// that exists before the first missing one, and the first missing page or 0.
+func (p *paginator) probeInOrder(ctx context.Context, moodLink string, pages []int, last int) (int, int) {
+	exists := make([]bool, len(pages))
+	var wg sync.WaitGroup
+	for i, n := range pages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			exists[i] = p.exists(ctx, pageURL(moodLink, n))
+		}()
+	}
+	wg.Wait()
+
+	for i, n := range pages {
+		if !exists[i] {
+			return last, n
+		}
+		last = n
+	}
+	return last, 0
+}
+
+// exists reports whether the page is there, every page is missing once ctx is done.
+func (p *paginator) exists(ctx context.Context, paginatedURL string) bool {
+	_, err := p.prober.Fetch(ctx, paginatedURL)
+	if ctx.Err() != nil {
+		return false
+	}
+	var statusErr *page.StatusError
+	if errors.As(err, &statusErr) {
+		logging.From(ctx).Debug("Listing page not found", logging.KeyPageURL, paginatedURL, "status", statusErr.StatusCode)
+		return false
+	}
+	if err != nil {
+		logging.From(ctx).Warn("could not check listing page, assuming it does not exist", logging.KeyPageURL, paginatedURL, "error", err)
+		return false
+	}
+	return true
+}
+
+// countFromLinks reads the highest page number from the pagination links of the first
+// page, or returns 0 when there are none.
+func (p *paginator) countFromLinks(first *page.Document) int {
+	if p.selectors.Listing.PageLinks.CSS == "" || first == nil {
+		return 0
+	}
+	links, err := p.selectors.Listing.PageLinks.FindAll(first.Element)
+	if err != nil || len(links) == 0 {
+		return 0
+	}
+
+	count := 1
+	for _, link := range links {
+		if href, err := link.GetAttribute(p.selectors.Attributes.Link); err == nil {
+			if m := pageNumberPattern.FindStringSubmatch(href); m != nil {
+				n, _ := strconv.Atoi(m[1])
+				count = max(count, n)
+			}
+		}
+		if text, err := link.Text(); err == nil {
+			if n, err := strconv.Atoi(text); err == nil {
+				count = max(count, n)
+			}
+		}
+	}
+	return count
+}
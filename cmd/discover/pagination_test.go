@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"song-sc/internal/fakesite"
+	"song-sc/internal/page"
+)
+
+func TestDiscoverFakesite(t *testing.T) {
+	selectors := loadProfile(t)
+	tests := []struct {
+		name  string
+		opts  fakesite.Options
+		first bool // pass the first page to discover
+	}{
+		{"pagination links", fakesite.Options{Moods: 1, ItemsPerMood: 10, PageSize: 3}, true},
+		{"hidden pagination", fakesite.Options{Moods: 1, ItemsPerMood: 10, PageSize: 3, HidePagination: true}, true},
+		{"first page missing", fakesite.Options{Moods: 1, ItemsPerMood: 10, PageSize: 3}, false},
+		{"single page", fakesite.Options{Moods: 1, ItemsPerMood: 2, PageSize: 3, HidePagination: true}, true},
+		{"many pages", fakesite.Options{Moods: 1, ItemsPerMood: 61, PageSize: 2, HidePagination: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := fakesite.NewCatalog(tt.opts)
+			server := httptest.NewServer(fakesite.NewServer(catalog))
+			defer server.Close()
+
+			ctx := context.Background()
+			fetcher := page.NewHTTPFetcher()
+			moodLink := server.URL + "/moods/" + catalog.Moods[0].Slug
+			first, err := fetcher.Fetch(ctx, moodLink)
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			docs := []*page.Document{first}
+			if !tt.first {
+				first = nil
+			}
+
+			var pages []int
+			p := &paginator{prober: fetcher, selectors: selectors}
+			p.discover(ctx, moodLink, first, func(n int) {
+				pages = append(pages, n)
+				doc, err := fetcher.Fetch(ctx, pageURL(moodLink, n))
+				if err != nil {
+					t.Fatalf("Fetch(page %d) error = %v", n, err)
+				}
+				docs = append(docs, doc)
+			})
+
+			var want []int
+			for n := 2; n <= catalog.Pages(catalog.Moods[0]); n++ {
+				want = append(want, n)
+			}
+			if !slices.Equal(pages, want) {
+				t.Fatalf("discovered pages %v, want %v", pages, want)
+			}
+
+			var got []string
+			for _, doc := range docs {
+				for _, card := range listingCards(t, doc, selectors) {
+					item, warnings, err := extractCard(card, selectors)
+					if err != nil || len(warnings) > 0 {
+						t.Errorf("%s: warnings = %v, error = %v", item.ItemURL, warnings, err)
+					}
+					got = append(got, item.ItemURL)
+				}
+			}
+			var wantItems []string
+			for _, item := range catalog.Moods[0].Items {
+				wantItems = append(wantItems, server.URL+"/item/"+item.Slug+"/")
+			}
+			if !slices.Equal(got, wantItems) {
+				t.Errorf("extracted items %v, want %v", got, wantItems)
+			}
+		})
+	}
+}
+
+func TestDiscoverCancelled(t *testing.T) {
+	catalog := fakesite.NewCatalog(fakesite.Options{Moods: 1, ItemsPerMood: 30, PageSize: 3, HidePagination: true})
+	server := httptest.NewServer(fakesite.NewServer(catalog))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := &paginator{prober: page.NewHTTPFetcher(), selectors: loadProfile(t)}
+	p.discover(ctx, server.URL+"/moods/mood-1", nil, func(n int) {
+		t.Errorf("found page %d after ctx was done", n)
+	})
+}
@@ -0,0 +1,53 @@
+// Command dlq inspects and replays the dead-letter queue of the items topic.
+//
+//	dlq list
+//	dlq replay [-id MESSAGE_ID]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"song-sc/internal/queue"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: dlq list | dlq replay [-id MESSAGE_ID]")
+		os.Exit(2)
+	}
+
+	conn, err := queue.Dial(context.Background(), os.Getenv("RABBITMQ_URL"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	switch os.Args[1] {
+	case "list":
+		deadLetters, err := queue.Items.DeadLetters(context.Background(), conn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, dl := range deadLetters {
+			fmt.Printf("%s\tattempts=%d\tdead_at=%s\terror=%s\n", dl.MessageID, dl.Attempts, dl.DeadAt, dl.LastError)
+			fmt.Printf("\t%s\n", dl.Body)
+		}
+		fmt.Printf("%d dead letters\n", len(deadLetters))
+	case "replay":
+		fs := flag.NewFlagSet("replay", flag.ExitOnError)
+		id := fs.String("id", "", "replay only the message with this ID")
+		_ = fs.Parse(os.Args[2:])
+		replayed, err := queue.Items.ReplayDeadLetters(context.Background(), conn, *id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("replayed %d messages\n", replayed)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"song-sc/internal/fakesite"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	moods := flag.Int("moods", fakesite.DefaultOptions.Moods, "number of moods")
+	items := flag.Int("items", fakesite.DefaultOptions.ItemsPerMood, "number of items per mood")
+	pageSize := flag.Int("page-size", fakesite.DefaultOptions.PageSize, "number of items per listing page")
+	hidePagination := flag.Bool("hide-pagination", false, "leave pagination links out of listing pages")
+	flag.Parse()
+
+	catalog := fakesite.NewCatalog(fakesite.Options{
+		Moods:          *moods,
+		ItemsPerMood:   *items,
+		PageSize:       *pageSize,
+		HidePagination: *hidePagination,
+	})
+	log.Printf("Serving fake SongSara with %d moods on %s", len(catalog.Moods), *addr)
+	log.Fatal(http.ListenAndServe(*addr, fakesite.NewServer(catalog)))
+}
@@ -1,10 +0,0 @@
-module finall-data
-
-go 1.24.2
-
-require (
-	github.com/rabbitmq/amqp091-go v1.10.0
-	github.com/tebeka/selenium v0.9.9
-)
-
-require github.com/blang/semver v3.5.1+incompatible // indirect
@@ -0,0 +1,164 @@
+// Package cache remembers scraped taxonomy entities (artists, instruments, genres, moods
+// and publishers) by canonical URL, so an entity page is fetched once per cache TTL no
+// matter how many items, workers or runs link to it. Fetching and storing are tracked
+// apart: the cache file outlives the sink it was filled for, so an entity found in it is
+// still stored once per run by the process that serves it.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"song-sc/internal/journal"
+	"song-sc/internal/model"
+)
+
+// Cache is an in-memory map backed by an append-only JSON Lines file. It is safe for
+// concurrent use, concurrent lookups of the same missing key share a single load.
+type Cache struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]entry
+	inflight map[string]*call
+	stored   map[string]*store
+	file     *journal.Journal[entry]
+}
+
+type entry struct {
+	Key      string          `json:"key"`
+	Value    json.RawMessage `json:"value"`
+	StoredAt time.Time       `json:"stored_at"`
+}
+
+// store is a save of an entity to the sink during run, done is closed once it finished.
+type store struct {
+	run  string
+	done chan struct{}
+	err  error
+}
+
+type call struct {
+	done  chan struct{}
+	value json.RawMessage
+	err   error
+}
+
+// Open loads the cache file at path, dropping entries older than ttl, and compacts it.
+func Open(path string, ttl time.Duration) (*Cache, error) {
+	c := &Cache{
+		ttl:      ttl,
+		inflight: make(map[string]*call),
+		stored:   make(map[string]*store),
+	}
+	file, entries, err := journal.Open(path, func(e entry) string { return e.Key }, c.fresh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open entity cache: %w", err)
+	}
+	c.file, c.entries = file, entries
+	return c, nil
+}
+
+func (c *Cache) fresh(e entry) bool {
+	return c.ttl <= 0 || time.Since(e.StoredAt) < c.ttl
+}
+
+// Purge drops every cached entity, for full crawls that fetch everything again.
+func (c *Cache) Purge() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]entry)
+	if err := c.file.Truncate(); err != nil {
+		return fmt.Errorf("failed to purge entity cache: %w", err)
+	}
+	return nil
+}
+
+// Close closes the cache file, entries already written stay on disk for the next run.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.Close()
+}
+
+// Get returns the value cached under kind and the canonical form of rawURL. On a miss or
+// an entry older than the TTL, it calls load, caches its result and reports cached as
+// false, so the caller knows the value was just scraped.
+func Get[T any](c *Cache, kind, rawURL string, load func() (T, error)) (value T, cached bool, err error) {
+	key := kind + " " + model.CanonicalURL(rawURL)
+	raw, cached, err := c.get(key, func() (json.RawMessage, error) {
+		v, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	})
+	if err != nil {
+		return value, false, err
+	}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return value, false, fmt.Errorf("corrupted cache entry %s: %w", key, err)
+	}
+	return value, cached, nil
+}
+
+func (c *Cache) get(key string, load func() (json.RawMessage, error)) (json.RawMessage, bool, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && c.fresh(e) {
+		c.mu.Unlock()
+		return e.Value, true, nil
+	}
+	if cl, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-cl.done
+		return cl.value, cl.err == nil, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	c.inflight[key] = cl
+	c.mu.Unlock()
+
+	cl.value, cl.err = load()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if cl.err == nil {
+		e := entry{Key: key, Value: cl.value, StoredAt: time.Now().UTC()}
+		c.entries[key] = e
+		if err := c.file.Append(e); err != nil {
+			cl.err = fmt.Errorf("failed to write entity cache: %w", err)
+		}
+	}
+	c.mu.Unlock()
+	close(cl.done)
+	return cl.value, false, cl.err
+}
+
+// Store calls save unless the entity under kind and rawURL was already saved during run by
+// this process. Concurrent stores of the same entity share a single save, a failed save is
+// tried again by the next caller.
+func (c *Cache) Store(run, kind, rawURL string, save func() error) error {
+	key := kind + " " + model.CanonicalURL(rawURL)
+	c.mu.Lock()
+	if s, ok := c.stored[key]; ok && s.run == run {
+		c.mu.Unlock()
+		<-s.done
+		return s.err
+	}
+	s := &store{run: run, done: make(chan struct{})}
+	c.stored[key] = s
+	c.mu.Unlock()
+
+	s.err = save()
+
+	if s.err != nil {
+		c.mu.Lock()
+		if c.stored[key] == s {
+			delete(c.stored, key)
+		}
+		c.mu.Unlock()
+	}
+	close(s.done)
+	return s.err
+}
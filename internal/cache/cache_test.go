@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func open(t *testing.T, path string, ttl time.Duration) *Cache {
+	t.Helper()
+	c, err := Open(path, ttl)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// loader counts how often it was called and returns value.
+func loader(calls *atomic.Int32, value string) func() (string, error) {
+	return func() (string, error) {
+		calls.Add(1)
+		return value, nil
+	}
+}
+
+func TestGet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	c := open(t, path, 0)
+	var calls atomic.Int32
+
+	tests := []struct {
+		url        string
+		wantCached bool
+	}{
+		{"https://songsara.net/artist/a/", false},
+		{"https://songsara.net/artist/a", true},
+		{"HTTPS://SongSara.net/artist/a/?x=1", true},
+		{"https://songsara.net/artist/b/", false},
+	}
+	for _, tt := range tests {
+		value, cached, err := Get(c, "artist", tt.url, loader(&calls, tt.url))
+		if err != nil {
+			t.Fatalf("Get(%q) error = %v", tt.url, err)
+		}
+		if cached != tt.wantCached {
+			t.Errorf("Get(%q) cached = %v, want %v", tt.url, cached, tt.wantCached)
+		}
+		if !cached && value != tt.url {
+			t.Errorf("Get(%q) = %q, want the loaded value", tt.url, value)
+		}
+	}
+	if _, cached, _ := Get(c, "instrument", "https://songsara.net/artist/a/", loader(&calls, "i")); cached {
+		t.Error("Get() of another kind hit the artist entry")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("load was called %d times, want 3", calls.Load())
+	}
+
+	// a later run of another process reads the file
+	_ = c.Close()
+	c = open(t, path, 0)
+	value, cached, err := Get(c, "artist", "https://songsara.net/artist/a/", loader(&calls, "new"))
+	if err != nil || !cached || value != "https://songsara.net/artist/a/" {
+		t.Errorf("Get() after reopening = %q, %v, %v, want the cached value", value, cached, err)
+	}
+}
+
+func TestGetFailedLoad(t *testing.T) {
+	c := open(t, filepath.Join(t.TempDir(), "cache.jsonl"), 0)
+	failed := errors.New("site down")
+	if _, _, err := Get(c, "artist", "a", func() (string, error) { return "", failed }); !errors.Is(err, failed) {
+		t.Fatalf("Get() error = %v, want %v", err, failed)
+	}
+	var calls atomic.Int32
+	if _, cached, err := Get(c, "artist", "a", loader(&calls, "v")); err != nil || cached || calls.Load() != 1 {
+		t.Errorf("Get() after a failed load: cached = %v, error = %v, loads = %d, want a fresh load", cached, err, calls.Load())
+	}
+}
+
+func TestGetSharesLoad(t *testing.T) {
+	c := open(t, filepath.Join(t.TempDir(), "cache.jsonl"), 0)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func() (string, error) {
+		calls.Add(1)
+		<-release
+		return "v", nil
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, _, err := Get(c, "artist", "a", load); err != nil || value != "v" {
+				t.Errorf("Get() = %q, %v", value, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("load was called %d times, want 1", calls.Load())
+	}
+}
+
+func TestTTL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	old := `{"key":"artist old","value":"\"old\"","stored_at":"2000-01-01T00:00:00Z"}` + "\n"
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := open(t, path, 50*time.Millisecond)
+	var calls atomic.Int32
+
+	if _, cached, _ := Get(c, "artist", "old", loader(&calls, "v")); cached {
+		t.Error("an entry older than the TTL was loaded from the file")
+	}
+	if _, cached, _ := Get(c, "artist", "old", loader(&calls, "v")); !cached {
+		t.Error("a fresh entry was not cached")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, cached, _ := Get(c, "artist", "old", loader(&calls, "v")); cached {
+		t.Error("an entry that expired in memory was served")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	c := open(t, path, 0)
+	var calls atomic.Int32
+	_, _, _ = Get(c, "artist", "a", loader(&calls, "v"))
+	if err := c.Purge(); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if _, cached, _ := Get(c, "artist", "a", loader(&calls, "v")); cached {
+		t.Error("Get() after Purge() hit the cache")
+	}
+
+	_ = c.Close()
+	c = open(t, path, 0)
+	if len(c.entries) != 1 {
+		t.Errorf("reopened cache holds %d entries, want only the one loaded after Purge", len(c.entries))
+	}
+}
+
+func TestStore(t *testing.T) {
+	c := open(t, filepath.Join(t.TempDir(), "cache.jsonl"), 0)
+	var saves atomic.Int32
+	save := func() error { saves.Add(1); return nil }
+	failed := errors.New("disk full")
+
+	steps := []struct {
+		run, url string
+		save     func() error
+		wantErr  error
+		want     int32 // saves so far
+	}{
+		{"r1", "https://songsara.net/artist/a/", save, nil, 1},
+		{"r1", "https://songsara.net/artist/a", save, nil, 1},
+		{"r2", "https://songsara.net/artist/a/", save, nil, 2},
+		{"r2", "https://songsara.net/artist/b/", func() error { saves.Add(1); return failed }, failed, 3},
+		{"r2", "https://songsara.net/artist/b/", save, nil, 4},
+		{"r2", "https://songsara.net/artist/b/", save, nil, 4},
+	}
+	for i, step := range steps {
+		if err := c.Store(step.run, "artist", step.url, step.save); !errors.Is(err, step.wantErr) {
+			t.Errorf("step %d: Store() error = %v, want %v", i, err, step.wantErr)
+		}
+		if saves.Load() != step.want {
+			t.Errorf("step %d: %d saves, want %d", i, saves.Load(), step.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package crawlstate remembers what previous crawl runs saw, so a run only does the work
+// for pages that are new or changed since.
+package crawlstate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"song-sc/internal/journal"
+	"song-sc/internal/model"
+)
+
+// Entry is the state of a single key, usually an item URL.
+type Entry struct {
+	Key  string `json:"key"`
+	Hash string `json:"hash"`
+	// Source is the hash of the input the entry was built from, detail records the listing
+	// card it was handed so discovery can tell which cards were processed.
+	Source    string    `json:"source,omitempty"`
+	LastSeen  time.Time `json:"last_seen"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+var errReadOnly = errors.New("crawl state was opened read-only")
+
+// State is an in-memory map backed by an append-only JSON Lines file, compacted on Open.
+// It is safe for concurrent use.
+type State struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+	file    *journal.Journal[Entry]
+}
+
+// Open loads the state file at path, creating it when missing.
+func Open(path string) (*State, error) {
+	file, entries, err := journal.Open(path, func(e Entry) string { return e.Key }, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open crawl state: %w", err)
+	}
+	return &State{entries: entries, file: file}, nil
+}
+
+// Read loads the state file at path without writing to it, for reading the state of
+// another stage while it runs. Recording into the returned State fails.
+func Read(path string) (*State, error) {
+	entries, err := journal.Read(path, func(e Entry) string { return e.Key }, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read crawl state: %w", err)
+	}
+	return &State{entries: entries}, nil
+}
+
+// Changed reports whether key is unknown or was last recorded with a different hash.
+func (s *State) Changed(key, hash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	return !ok || e.Hash != hash
+}
+
+// Done reports whether key was last recorded from source.
+func (s *State) Done(key, source string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	return ok && e.Source == source
+}
+
+// Record stores hash as the current state of key and marks it as seen now.
+func (s *State) Record(key, hash string) error {
+	return s.RecordFrom(key, hash, "")
+}
+
+// RecordFrom is Record for state built from the input whose hash is source.
+func (s *State) RecordFrom(key, hash, source string) error {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return errReadOnly
+	}
+	e, ok := s.entries[key]
+	if !ok || e.Hash != hash {
+		e = Entry{Key: key, Hash: hash, ChangedAt: now}
+	}
+	e.Source = source
+	e.LastSeen = now
+	s.entries[key] = e
+	if err := s.file.Append(e); err != nil {
+		return fmt.Errorf("failed to write crawl state: %w", err)
+	}
+	return nil
+}
+
+// Close closes the state file.
+func (s *State) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// Hash returns a hex SHA-256 of the JSON encoding of v, for use as the hash of a record.
+func Hash(v any) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Key combines the mood an item was found in with its URL, since the same item is stored
+// once per mood.
+func Key(mood, itemURL string) string {
+	return mood + " " + model.CanonicalURL(itemURL)
+}
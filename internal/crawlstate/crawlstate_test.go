@@ -0,0 +1,101 @@
+package crawlstate
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	steps := []struct {
+		record       bool
+		key, hash    string
+		source       string
+		wantChanged  bool
+		wantNewEntry bool // ChangedAt moves
+	}{
+		{false, "a", "h1", "", true, false},
+		{true, "a", "h1", "c1", true, true},
+		{true, "a", "h1", "c2", false, false},
+		{true, "a", "h2", "c2", true, true},
+		{true, "b", "h1", "", true, true},
+	}
+	var changedAt time.Time
+	for i, step := range steps {
+		if got := s.Changed(step.key, step.hash); got != step.wantChanged {
+			t.Errorf("step %d: Changed(%q, %q) = %v, want %v", i, step.key, step.hash, got, step.wantChanged)
+		}
+		if !step.record {
+			continue
+		}
+		if err := s.RecordFrom(step.key, step.hash, step.source); err != nil {
+			t.Fatalf("step %d: RecordFrom() error = %v", i, err)
+		}
+		e := s.entries[step.key]
+		if e.Hash != step.hash || e.Source != step.source || e.LastSeen.IsZero() {
+			t.Errorf("step %d: entry = %+v", i, e)
+		}
+		if step.key == "a" && (e.ChangedAt != changedAt) != step.wantNewEntry {
+			t.Errorf("step %d: ChangedAt moved = %v, want %v", i, e.ChangedAt != changedAt, step.wantNewEntry)
+		}
+		if step.key == "a" {
+			changedAt = e.ChangedAt
+		}
+	}
+	if !s.Done("a", "c2") || s.Done("a", "c1") || s.Done("b", "c1") || s.Done("missing", "") {
+		t.Error("Done() does not match the recorded sources")
+	}
+
+	// another process reads the state while s keeps appending
+	read, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !read.Done("a", "c2") || read.Changed("a", "h2") || !read.Done("b", "") {
+		t.Error("Read() does not see the recorded entries")
+	}
+	if err := read.Record("c", "h"); !errors.Is(err, errReadOnly) {
+		t.Errorf("Record() into a read-only state error = %v, want %v", err, errReadOnly)
+	}
+	if err := read.Close(); err != nil {
+		t.Errorf("Close() of a read-only state error = %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s.Close()
+	if len(s.entries) != 2 || s.Changed("a", "h2") || !s.Done("a", "c2") {
+		t.Errorf("reopened state = %v, want a and b as last recorded", s.entries)
+	}
+}
+
+func TestKey(t *testing.T) {
+	if Key("Mood", "https://songsara.net/item/x/") != Key("Mood", "https://SongSara.net/item/x") {
+		t.Error("Key() depends on the spelling of the URL")
+	}
+	if Key("Mood 1", "https://songsara.net/item/x/") == Key("Mood 2", "https://songsara.net/item/x/") {
+		t.Error("Key() of the same item in two moods collide")
+	}
+}
+
+func TestHash(t *testing.T) {
+	type card struct{ Name, URL string }
+	if Hash(card{"a", "u"}) != Hash(card{"a", "u"}) {
+		t.Error("Hash() is not stable")
+	}
+	if Hash(card{"a", "u"}) == Hash(card{"b", "u"}) {
+		t.Error("Hash() of different values collide")
+	}
+}
@@ -0,0 +1,233 @@
+// Package diff compares two crawl runs, either through their manifests or through the
+// catalogs they wrote, and reports what was added, removed and changed.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"song-sc/internal/manifest"
+	"song-sc/internal/model"
+	"song-sc/internal/store"
+)
+
+const albumType = "آلبوم"
+
+type Report struct {
+	From     string      `json:"from"`
+	To       string      `json:"to"`
+	Sections []Section   `json:"sections"`
+	Moods    []MoodDelta `json:"moods,omitempty"`
+}
+
+// Section lists the differences of one kind of record.
+type Section struct {
+	Name    string  `json:"name"`
+	Added   []Entry `json:"added"`
+	Removed []Entry `json:"removed"`
+	Changed []Entry `json:"changed"`
+}
+
+type Entry struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Fields holds the JSON fields that differ, for changed records only.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// MoodDelta is the number of items of a mood in both runs.
+type MoodDelta struct {
+	Mood   string `json:"mood"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+}
+
+// Empty reports whether the runs did not differ.
+func (r Report) Empty() bool {
+	for _, s := range r.Sections {
+		if len(s.Added)+len(s.Removed)+len(s.Changed) > 0 {
+			return false
+		}
+	}
+	for _, m := range r.Moods {
+		if m.Before != m.After {
+			return false
+		}
+	}
+	return true
+}
+
+// Manifests compares the items two runs saw. Album items are reported as albums, other
+// items as items, artists and genres are the names found on the listing cards. Manifests
+// hold no tracks, track-level changes need the catalogs.
+func Manifests(a, b *manifest.Manifest) Report {
+	report := Report{From: a.RunID, To: b.RunID}
+
+	albums := func(m *manifest.Manifest) map[string]manifest.Item {
+		return itemsOf(m, func(item manifest.Item) bool { return item.Type == albumType })
+	}
+	others := func(m *manifest.Manifest) map[string]manifest.Item {
+		return itemsOf(m, func(item manifest.Item) bool { return item.Type != albumType })
+	}
+	itemName := func(item manifest.Item) string { return item.Mood + ": " + item.Name }
+	report.Sections = append(report.Sections,
+		compareBy("albums", albums(a), albums(b), itemName, func(x, y manifest.Item) bool { return x.Hash == y.Hash }),
+		compareBy("items", others(a), others(b), itemName, func(x, y manifest.Item) bool { return x.Hash == y.Hash }),
+		compareNames("artists", names(a, func(item manifest.Item) string { return item.Artist }), names(b, func(item manifest.Item) string { return item.Artist })),
+		compareNames("genres", names(a, func(item manifest.Item) string { return item.Genre }), names(b, func(item manifest.Item) string { return item.Genre })),
+	)
+
+	moods := make(map[string]bool)
+	for mood := range a.Moods {
+		moods[mood] = true
+	}
+	for mood := range b.Moods {
+		moods[mood] = true
+	}
+	for _, mood := range slices.Sorted(maps.Keys(moods)) {
+		delta := MoodDelta{Mood: mood}
+		if stats, ok := a.Moods[mood]; ok {
+			delta.Before = stats.Items
+		}
+		if stats, ok := b.Moods[mood]; ok {
+			delta.After = stats.Items
+		}
+		report.Moods = append(report.Moods, delta)
+	}
+	return report
+}
+
+func itemsOf(m *manifest.Manifest, keep func(manifest.Item) bool) map[string]manifest.Item {
+	items := make(map[string]manifest.Item)
+	for _, item := range m.Items {
+		if keep(item) {
+			items[item.Mood+" "+item.URL] = item
+		}
+	}
+	return items
+}
+
+// names collects the names of m, a card lists several artists or genres separated by commas.
+func names(m *manifest.Manifest, name func(manifest.Item) string) map[string]string {
+	set := make(map[string]string)
+	for _, item := range m.Items {
+		for _, n := range strings.FieldsFunc(name(item), func(r rune) bool { return r == ',' || r == '،' }) {
+			if n = strings.TrimSpace(n); n != "" {
+				set[n] = n
+			}
+		}
+	}
+	return set
+}
+
+func compareNames(section string, a, b map[string]string) Section {
+	return compareBy(section, a, b, func(n string) string { return n }, func(x, y string) bool { return true })
+}
+
+// Catalogs compares two catalogs record by record.
+func Catalogs(fromName, toName string, a, b *store.Catalog) Report {
+	return Report{
+		From: fromName,
+		To:   toName,
+		Sections: []Section{
+			compare("albums", a.Albums, b.Albums, func(v model.Album) string { return v.Name }),
+			compare("tracks", a.Tracks, b.Tracks, func(v model.Track) string { return v.Title }),
+			compare("artists", a.Artists, b.Artists, func(v model.Artist) string { return v.NameEN }),
+			compare("instruments", a.Instruments, b.Instruments, func(v model.Instrument) string { return v.NameEN }),
+			compare("genres", a.Genres, b.Genres, func(v model.Genre) string { return v.NameEN }),
+			compare("moods", a.Moods, b.Moods, func(v model.MoodData) string { return v.NameEN }),
+			compare("publishers", a.Publishers, b.Publishers, func(v model.Publisher) string { return v.NameEN }),
+		},
+	}
+}
+
+// compare reports records as changed when any of their JSON fields differ.
+func compare[T any](section string, a, b map[string]T, name func(T) string) Section {
+	s := compareBy(section, a, b, name, func(x, y T) bool { return len(changedFields(x, y)) == 0 })
+	for i, entry := range s.Changed {
+		s.Changed[i].Fields = changedFields(a[entry.ID], b[entry.ID])
+	}
+	return s
+}
+
+func compareBy[T any](section string, a, b map[string]T, name func(T) string, equal func(x, y T) bool) Section {
+	s := Section{Name: section, Added: []Entry{}, Removed: []Entry{}, Changed: []Entry{}}
+	for _, id := range slices.Sorted(maps.Keys(b)) {
+		before, ok := a[id]
+		switch {
+		case !ok:
+			s.Added = append(s.Added, Entry{ID: id, Name: name(b[id])})
+		case !equal(before, b[id]):
+			s.Changed = append(s.Changed, Entry{ID: id, Name: name(b[id])})
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(a)) {
+		if _, ok := b[id]; !ok {
+			s.Removed = append(s.Removed, Entry{ID: id, Name: name(a[id])})
+		}
+	}
+	return s
+}
+
+func changedFields(a, b any) []string {
+	var x, y map[string]json.RawMessage
+	dataA, _ := json.Marshal(a)
+	dataB, _ := json.Marshal(b)
+	_ = json.Unmarshal(dataA, &x)
+	_ = json.Unmarshal(dataB, &y)
+	var fields []string
+	for key := range x {
+		if string(x[key]) != string(y[key]) {
+			fields = append(fields, key)
+		}
+	}
+	for key := range y {
+		if _, ok := x[key]; !ok {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// WriteText writes the report for people, one line per difference.
+func (r Report) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "diff %s -> %s\n", r.From, r.To); err != nil {
+		return err
+	}
+	for _, s := range r.Sections {
+		fmt.Fprintf(w, "\n%s: %d added, %d removed, %d changed\n", s.Name, len(s.Added), len(s.Removed), len(s.Changed))
+		for _, e := range s.Added {
+			fmt.Fprintf(w, "  + %s\t%s\n", e.ID, e.Name)
+		}
+		for _, e := range s.Removed {
+			fmt.Fprintf(w, "  - %s\t%s\n", e.ID, e.Name)
+		}
+		for _, e := range s.Changed {
+			if len(e.Fields) > 0 {
+				fmt.Fprintf(w, "  ~ %s\t%s\t%v\n", e.ID, e.Name, e.Fields)
+			} else {
+				fmt.Fprintf(w, "  ~ %s\t%s\n", e.ID, e.Name)
+			}
+		}
+	}
+	if len(r.Moods) > 0 {
+		fmt.Fprintf(w, "\nitems per mood:\n")
+		for _, m := range r.Moods {
+			fmt.Fprintf(w, "  %s\t%d -> %d\n", m.Mood, m.Before, m.After)
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
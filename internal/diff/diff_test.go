@@ -0,0 +1,152 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"song-sc/internal/manifest"
+	"song-sc/internal/model"
+	"song-sc/internal/store"
+)
+
+func newManifest(runID string, items ...manifest.Item) *manifest.Manifest {
+	m := manifest.New(runID, "discover", false)
+	for _, item := range items {
+		m.AddItem(item)
+		m.Update(item.Mood, func(stats *manifest.MoodStats) { stats.Items++ })
+	}
+	return m
+}
+
+// ids returns the ids of every entry of the section, prefixed with +, - or ~.
+func ids(r Report, section string) []string {
+	out := []string{}
+	for _, s := range r.Sections {
+		if s.Name != section {
+			continue
+		}
+		for _, e := range s.Added {
+			out = append(out, "+"+e.ID)
+		}
+		for _, e := range s.Removed {
+			out = append(out, "-"+e.ID)
+		}
+		for _, e := range s.Changed {
+			out = append(out, "~"+e.ID)
+		}
+	}
+	return out
+}
+
+func TestManifests(t *testing.T) {
+	album := manifest.Item{Mood: "m", URL: "a", Name: "Album", Type: albumType, Artist: "Artist 1, Artist 2", Genre: "Genre", Hash: "1"}
+	single := manifest.Item{Mood: "m", URL: "s", Name: "Single", Type: "تک آهنگ", Artist: "Artist 1", Genre: "Genre", Hash: "1"}
+	changed := func(item manifest.Item) manifest.Item {
+		item.Hash = "2"
+		return item
+	}
+
+	tests := []struct {
+		name  string
+		a, b  *manifest.Manifest
+		want  map[string][]string
+		empty bool
+	}{
+		{
+			name:  "same items",
+			a:     newManifest("a", album, single),
+			b:     newManifest("b", album, single),
+			want:  map[string][]string{"albums": {}, "items": {}, "artists": {}, "genres": {}},
+			empty: true,
+		},
+		{
+			name: "added album with two artists",
+			a:    newManifest("a", single),
+			b:    newManifest("b", album, single),
+			want: map[string][]string{"albums": {"+m a"}, "items": {}, "artists": {"+Artist 2"}, "genres": {}},
+		},
+		{
+			name: "removed and changed items",
+			a:    newManifest("a", album, single),
+			b:    newManifest("b", changed(album)),
+			want: map[string][]string{"albums": {"~m a"}, "items": {"-m s"}, "artists": {}, "genres": {}},
+		},
+		{
+			name: "persian comma",
+			a:    newManifest("a", single),
+			b:    newManifest("b", manifest.Item{Mood: "m", URL: "s", Artist: "Artist 1 ، Artist 3", Genre: "Genre", Hash: "1"}),
+			want: map[string][]string{"albums": {}, "items": {}, "artists": {"+Artist 3"}, "genres": {}},
+		},
+	}
+	for _, tt := range tests {
+		report := Manifests(tt.a, tt.b)
+		for section, want := range tt.want {
+			if got := ids(report, section); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: %s %v, want %v", tt.name, section, got, want)
+			}
+		}
+		if report.Empty() != tt.empty {
+			t.Errorf("%s: empty %v, want %v", tt.name, report.Empty(), tt.empty)
+		}
+	}
+}
+
+func TestManifestsMoods(t *testing.T) {
+	item := manifest.Item{Mood: "m", URL: "a", Hash: "1"}
+	b := newManifest("b", item)
+	b.Update("other", func(stats *manifest.MoodStats) {})
+	report := Manifests(newManifest("a", item), b)
+	want := []MoodDelta{{Mood: "m", Before: 1, After: 1}, {Mood: "other"}}
+	if !reflect.DeepEqual(report.Moods, want) {
+		t.Errorf("moods %+v, want %+v", report.Moods, want)
+	}
+	if !report.Empty() {
+		t.Error("a mood without items made the report differ")
+	}
+	b.Update("other", func(stats *manifest.MoodStats) { stats.Items++ })
+	if Manifests(newManifest("a", item), b).Empty() {
+		t.Error("a changed item count did not make the report differ")
+	}
+}
+
+func TestCatalogs(t *testing.T) {
+	catalog := func(tracks ...model.Track) *store.Catalog {
+		c := &store.Catalog{Tracks: make(map[string]model.Track)}
+		for _, track := range tracks {
+			c.Tracks[track.ID] = track
+		}
+		return c
+	}
+	track := model.Track{ID: "t1", Title: "One", Duration: "03:00"}
+	longer := track
+	longer.Duration = "04:00"
+	other := model.Track{ID: "t2", Title: "Two"}
+
+	tests := []struct {
+		name   string
+		a, b   *store.Catalog
+		want   []string
+		fields []string
+	}{
+		{name: "same", a: catalog(track), b: catalog(track), want: []string{}},
+		{name: "added", a: catalog(track), b: catalog(track, other), want: []string{"+t2"}},
+		{name: "removed", a: catalog(track, other), b: catalog(track), want: []string{"-t2"}},
+		{name: "changed", a: catalog(track), b: catalog(longer), want: []string{"~t1"}, fields: []string{"duration"}},
+	}
+	for _, tt := range tests {
+		report := Catalogs("a", "b", tt.a, tt.b)
+		if got := ids(report, "tracks"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: tracks %v, want %v", tt.name, got, tt.want)
+		}
+		if report.Empty() != (len(tt.want) == 0) {
+			t.Errorf("%s: empty %v", tt.name, report.Empty())
+		}
+		for _, s := range report.Sections {
+			for _, e := range s.Changed {
+				if !reflect.DeepEqual(e.Fields, tt.fields) {
+					t.Errorf("%s: changed fields %v, want %v", tt.name, e.Fields, tt.fields)
+				}
+			}
+		}
+	}
+}
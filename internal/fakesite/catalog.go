@@ -0,0 +1,148 @@
+package fakesite
+
+import "fmt"
+
+const (
+	TypeAlbum  = "آلبوم"
+	TypeSingle = "تک آهنگ"
+)
+
+type Options struct {
+	Moods        int
+	ItemsPerMood int
+	PageSize     int
+	// HidePagination leaves the pagination links out of listing pages, so the scraper has
+	// to probe for pages.
+	HidePagination bool
+}
+
+var DefaultOptions = Options{Moods: 3, ItemsPerMood: 7, PageSize: 3}
+
+type Catalog struct {
+	Moods       []*Mood
+	Items       map[string]*Item
+	Artists     map[string]*Taxon
+	Instruments map[string]*Taxon
+	Genres      map[string]*Taxon
+	PageSize    int
+	Paginated   bool
+}
+
+type Mood struct {
+	Slug  string
+	Name  string
+	Items []*Item
+}
+
+type Taxon struct {
+	Slug        string
+	NameEN      string
+	NameFA      string
+	Description string
+}
+
+type Item struct {
+	Slug        string
+	Name        string
+	Type        string
+	Date        string
+	Publisher   string
+	Artists     []*Taxon
+	Genres      []*Taxon
+	Moods       []*Taxon
+	Instruments []*Taxon
+	Tracks      []Track
+}
+
+type Track struct {
+	Title    string
+	Info     string
+	Duration string
+	Slug     string
+}
+
+// NewCatalog builds a deterministic synthetic catalog, the same options always produce
+// the same site.
+func NewCatalog(opts Options) *Catalog {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultOptions.PageSize
+	}
+	c := &Catalog{
+		Items:       make(map[string]*Item),
+		Artists:     make(map[string]*Taxon),
+		Instruments: make(map[string]*Taxon),
+		Genres:      make(map[string]*Taxon),
+		PageSize:    opts.PageSize,
+		Paginated:   !opts.HidePagination,
+	}
+
+	artists := taxa(c.Artists, "artist", "Artist", "هنرمند", 4)
+	instruments := taxa(c.Instruments, "instrument", "Instrument", "ساز", 3)
+	genres := taxa(c.Genres, "genre", "Genre", "سبک", 3)
+
+	for m := 0; m < opts.Moods; m++ {
+		mood := &Mood{Slug: fmt.Sprintf("mood-%d", m+1), Name: fmt.Sprintf("Mood %d", m+1)}
+		moodTaxon := &Taxon{Slug: mood.Slug, NameEN: mood.Name, NameFA: fmt.Sprintf("حس %d", m+1)}
+		for i := 0; i < opts.ItemsPerMood; i++ {
+			n := m*opts.ItemsPerMood + i
+			item := &Item{
+				Slug:        fmt.Sprintf("item-%d", n+1),
+				Name:        fmt.Sprintf("Item %d", n+1),
+				Type:        TypeSingle,
+				Date:        fmt.Sprintf("1403/%02d/%02d", n%12+1, n%28+1),
+				Publisher:   fmt.Sprintf("Publisher %d", n%2+1),
+				Artists:     []*Taxon{artists[n%len(artists)]},
+				Genres:      []*Taxon{genres[n%len(genres)]},
+				Moods:       []*Taxon{moodTaxon},
+				Instruments: []*Taxon{instruments[n%len(instruments)], instruments[(n+1)%len(instruments)]},
+			}
+			trackCount := 1
+			if n%2 == 0 {
+				item.Type = TypeAlbum
+				trackCount = 3
+			}
+			for t := 0; t < trackCount; t++ {
+				item.Tracks = append(item.Tracks, Track{
+					Title:    fmt.Sprintf("%s - Track %d", item.Name, t+1),
+					Info:     fmt.Sprintf("track %d of %s", t+1, item.Name),
+					Duration: fmt.Sprintf("0%d:%02d", 3+t, (n*7+t*11)%60),
+					Slug:     fmt.Sprintf("%s-%d", item.Slug, t+1),
+				})
+			}
+			mood.Items = append(mood.Items, item)
+			c.Items[item.Slug] = item
+		}
+		c.Moods = append(c.Moods, mood)
+	}
+	return c
+}
+
+func taxa(index map[string]*Taxon, kind, nameEN, nameFA string, count int) []*Taxon {
+	result := make([]*Taxon, 0, count)
+	for i := 1; i <= count; i++ {
+		t := &Taxon{
+			Slug:        fmt.Sprintf("%s-%d", kind, i),
+			NameEN:      fmt.Sprintf("%s %d", nameEN, i),
+			NameFA:      fmt.Sprintf("%s %d", nameFA, i),
+			Description: fmt.Sprintf("Synthetic description of %s %d.", nameEN, i),
+		}
+		index[t.Slug] = t
+		result = append(result, t)
+	}
+	return result
+}
+
+// Pages returns the number of listing pages of a mood.
+func (c *Catalog) Pages(m *Mood) int {
+	pages := (len(m.Items) + c.PageSize - 1) / c.PageSize
+	return max(pages, 1)
+}
+
+func (c *Catalog) mood(slug string) *Mood {
+	for _, m := range c.Moods {
+		if m.Slug == slug {
+			return m
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package fakesite serves a synthetic SongSara site with the same DOM the scrapers expect,
+// so the whole pipeline can run locally without touching songsara.net.
+package fakesite
+
+import (
+	"html/template"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type Server struct {
+	catalog *Catalog
+}
+
+func NewServer(catalog *Catalog) *Server {
+	return &Server{catalog: catalog}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(path.Clean(r.URL.Path), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "moods":
+		s.render(w, moodsTemplate, s.catalog.Moods)
+	case len(parts) == 2 && parts[0] == "moods":
+		s.serveMoodPage(w, parts[1], 1)
+	case len(parts) == 4 && parts[0] == "moods" && parts[2] == "page":
+		pageNumber, err := strconv.Atoi(parts[3])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.serveMoodPage(w, parts[1], pageNumber)
+	case len(parts) == 2 && parts[0] == "item":
+		item, ok := s.catalog.Items[parts[1]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		s.render(w, itemTemplate, struct {
+			*Item
+			Base string
+		}{Item: item, Base: baseURL(r)})
+	case len(parts) == 2 && parts[0] == "artist":
+		s.serveTaxon(w, r, s.catalog.Artists, parts[1])
+	case len(parts) == 2 && parts[0] == "instrument":
+		s.serveTaxon(w, r, s.catalog.Instruments, parts[1])
+	case len(parts) == 2 && parts[0] == "genre":
+		s.serveTaxon(w, r, s.catalog.Genres, parts[1])
+	case len(parts) == 2 && (parts[0] == "img" || parts[0] == "mp3"):
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write([]byte(parts[1]))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveMoodPage(w http.ResponseWriter, slug string, pageNumber int) {
+	mood := s.catalog.mood(slug)
+	if mood == nil || pageNumber < 1 || pageNumber > s.catalog.Pages(mood) {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	}
+	start := (pageNumber - 1) * s.catalog.PageSize
+	end := min(start+s.catalog.PageSize, len(mood.Items))
+	var pages []int
+	if s.catalog.Paginated {
+		for n := 1; n <= s.catalog.Pages(mood); n++ {
+			pages = append(pages, n)
+		}
+	}
+	s.render(w, moodTemplate, struct {
+		Mood  *Mood
+		Items []*Item
+		Pages []int
+		Page  int
+	}{Mood: mood, Items: mood.Items[start:end], Pages: pages, Page: pageNumber})
+}
+
+func (s *Server) serveTaxon(w http.ResponseWriter, r *http.Request, index map[string]*Taxon, slug string) {
+	taxon, ok := index[slug]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.render(w, taxonTemplate, taxon)
+}
+
+func (s *Server) render(w http.ResponseWriter, tmpl *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// baseURL is the absolute origin of the request, the player exposes absolute media links
+// just like the real site.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
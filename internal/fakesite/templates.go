@@ -0,0 +1,65 @@
+package fakesite
+
+import "html/template"
+
+var moodsTemplate = template.Must(template.New("moods").Parse(`<!DOCTYPE html>
+<html><head><title>Moods</title></head><body>
+<div class="box-i">
+{{- range .}}
+  <a href="/moods/{{.Slug}}"><img src="/img/{{.Slug}}.jpg"><h3>{{.Name}}</h3></a>
+{{- end}}
+</div>
+</body></html>`))
+
+var moodTemplate = template.Must(template.New("mood").Parse(`<!DOCTYPE html>
+<html><head><title>{{.Mood.Name}}</title></head><body>
+<div class="box-i">
+{{- range .Items}}
+  <div class="posting col-6 col-sm-4 col-md-3 col-lg-2 col-xl-2">
+    <a href="/item/{{.Slug}}/"><img src="/img/{{.Slug}}.jpg"></a>
+    <div class="TSale-txt"><span>{{.Date}}</span></div>
+    <div class="TSale-txt"><span>{{.Type}}</span></div>
+    <section>
+      <ul>
+        <li>{{.Name}}</li>
+        <li>{{range $i, $a := .Artists}}{{if $i}}, {{end}}{{$a.NameEN}}{{end}}</li>
+        <li>{{range $i, $g := .Genres}}{{if $i}}, {{end}}{{$g.NameEN}}{{end}}</li>
+        <li>{{.Date}}</li>
+      </ul>
+    </section>
+  </div>
+{{- end}}
+</div>
+{{- if .Pages}}
+<nav class="pagination">
+{{- $mood := .Mood}}{{$current := .Page}}
+{{- range .Pages}}
+  {{if eq . $current}}<span class="page-numbers current">{{.}}</span>{{else}}<a class="page-numbers" href="/moods/{{$mood.Slug}}/page/{{.}}/">{{.}}</a>{{end}}
+{{- end}}
+</nav>
+{{- end}}
+</body></html>`))
+
+var itemTemplate = template.Must(template.New("item").Parse(`<!DOCTYPE html>
+<html><head><title>{{.Name}}</title></head><body>
+<h1>{{.Name}}</h1>
+<div class="AR-Si">{{range .Artists}}<a href="/artist/{{.Slug}}/" title="{{.NameFA}}">{{.NameEN}}</a>{{end}}</div>
+<div class="genre-Si">{{range .Genres}}<a href="/genre/{{.Slug}}/" title="{{.NameFA}}">{{.NameEN}}</a>{{end}}</div>
+<div class="mood-Si">{{range .Moods}}<a href="/moods/{{.Slug}}" title="{{.NameFA}}">{{.NameEN}}</a>{{end}}</div>
+<div class="pub-Si">{{.Publisher}}</div>
+<div class="instrument-Si">{{range .Instruments}}<a href="/instrument/{{.Slug}}/" title="{{.NameFA}}">{{.NameEN}}</a>{{end}}</div>
+<div id="aramplayer">
+  <ul>
+{{- $item := .}}
+{{- range .Tracks}}
+    <li data-title="{{.Title}}" data-artist="{{(index $item.Artists 0).NameEN}}" data-album="{{$item.Name}}" data-info="{{.Info}}" data-image="{{$item.Base}}/img/{{$item.Slug}}.jpg" data-duration="{{.Duration}}" data-src="{{$item.Base}}/mp3/{{.Slug}}.mp3">{{.Title}}</li>
+{{- end}}
+  </ul>
+</div>
+</body></html>`))
+
+var taxonTemplate = template.Must(template.New("taxon").Parse(`<!DOCTYPE html>
+<html><head><title>{{.NameEN}}</title></head><body>
+<div class="artist-img"><img src="/img/{{.Slug}}.jpg"></div>
+<div class="h3-artist"><h3>{{.NameEN}}</h3><p>{{.Description}}</p></div>
+</body></html>`))
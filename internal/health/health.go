@@ -0,0 +1,169 @@
+// Package health serves the liveness and readiness endpoints of a pipeline stage.
+//
+// /healthz fails when a liveness check fails, something the stage cannot recover from on
+// its own, such as a dead browser session. /readyz also fails when a readiness check fails,
+// such as a lost broker connection the stage is still reconnecting, until the stage has
+// started and once it is shutting down. Both answer with the same JSON report of every
+// check.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// CheckTimeout bounds every single check.
+const CheckTimeout = 5 * time.Second
+
+// Check returns an error when the checked dependency is not usable.
+type Check func(ctx context.Context) error
+
+type check struct {
+	name string
+	live bool
+	fn   Check
+}
+
+// Health holds the checks of a stage and what it last achieved.
+type Health struct {
+	mu       sync.Mutex
+	checks   []check
+	lastItem time.Time
+	started  bool
+	stopping bool
+}
+
+func New() *Health {
+	return &Health{}
+}
+
+// Live adds a check that both endpoints depend on.
+func (h *Health) Live(name string, fn Check) {
+	h.add(check{name: name, live: true, fn: fn})
+}
+
+// Ready adds a check that only readiness depends on.
+func (h *Health) Ready(name string, fn Check) {
+	h.add(check{name: name, fn: fn})
+}
+
+func (h *Health) add(c check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, c)
+}
+
+// ItemDone records that an item was handled successfully just now.
+func (h *Health) ItemDone() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastItem = time.Now().UTC()
+}
+
+// Started marks the stage as done starting up, it is ready from then on unless a check
+// fails.
+func (h *Health) Started() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.started = true
+}
+
+// Stopping marks the stage as shutting down, it is not ready from then on.
+func (h *Health) Stopping() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stopping = true
+}
+
+// Report is the body of both endpoints.
+type Report struct {
+	Status   string            `json:"status"`
+	Starting bool              `json:"starting,omitempty"`
+	Stopping bool              `json:"stopping,omitempty"`
+	Checks   map[string]string `json:"checks"`
+	LastItem *time.Time        `json:"last_item,omitempty"`
+	// SinceLastItem is the time since LastItem in seconds.
+	SinceLastItem float64 `json:"since_last_item,omitempty"`
+}
+
+// run runs every check concurrently and reports whether the live and the ready checks
+// passed.
+func (h *Health) run(ctx context.Context) (report Report, live, ready bool) {
+	h.mu.Lock()
+	checks := append([]check(nil), h.checks...)
+	lastItem, started, stopping := h.lastItem, h.started, h.stopping
+	h.mu.Unlock()
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+			errs[i] = c.fn(ctx)
+		}()
+	}
+	wg.Wait()
+
+	report = Report{Starting: !started, Stopping: stopping, Checks: make(map[string]string, len(checks))}
+	live, ready = true, started && !stopping
+	for i, c := range checks {
+		if errs[i] == nil {
+			report.Checks[c.name] = "ok"
+			continue
+		}
+		report.Checks[c.name] = errs[i].Error()
+		ready = false
+		if c.live {
+			live = false
+		}
+	}
+	if !lastItem.IsZero() {
+		report.LastItem = &lastItem
+		report.SinceLastItem = time.Since(lastItem).Seconds()
+	}
+	return report, live, ready
+}
+
+// Register serves /healthz and /readyz on mux.
+func (h *Health) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		report, live, _ := h.run(r.Context())
+		respond(w, report, live)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		report, _, ready := h.run(r.Context())
+		respond(w, report, ready)
+	})
+}
+
+func respond(w http.ResponseWriter, report Report, ok bool) {
+	status := http.StatusOK
+	report.Status = "ok"
+	if !ok {
+		status = http.StatusServiceUnavailable
+		report.Status = "unavailable"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// Writable checks that files can be created in dir.
+func Writable(dir string) Check {
+	return func(ctx context.Context) error {
+		f, err := os.CreateTemp(dir, ".healthz-*")
+		if err != nil {
+			return fmt.Errorf("%s is not writable: %w", dir, err)
+		}
+		_ = f.Close()
+		return os.Remove(f.Name())
+	}
+}
@@ -0,0 +1,124 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEndpoints(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return errors.New("down") }
+
+	tests := []struct {
+		name     string
+		setup    func(h *Health)
+		healthz  int
+		readyz   int
+		checks   map[string]string
+		starting bool
+		stopping bool
+	}{
+		{
+			name:     "starting",
+			setup:    func(h *Health) { h.Live("browser", ok) },
+			healthz:  http.StatusOK,
+			readyz:   http.StatusServiceUnavailable,
+			checks:   map[string]string{"browser": "ok"},
+			starting: true,
+		},
+		{
+			name:    "started",
+			setup:   func(h *Health) { h.Live("browser", ok); h.Ready("broker", ok); h.Started() },
+			healthz: http.StatusOK,
+			readyz:  http.StatusOK,
+			checks:  map[string]string{"browser": "ok", "broker": "ok"},
+		},
+		{
+			name:    "failed readiness check",
+			setup:   func(h *Health) { h.Live("browser", ok); h.Ready("broker", failing); h.Started() },
+			healthz: http.StatusOK,
+			readyz:  http.StatusServiceUnavailable,
+			checks:  map[string]string{"browser": "ok", "broker": "down"},
+		},
+		{
+			name:    "failed liveness check",
+			setup:   func(h *Health) { h.Live("browser", failing); h.Ready("broker", ok); h.Started() },
+			healthz: http.StatusServiceUnavailable,
+			readyz:  http.StatusServiceUnavailable,
+			checks:  map[string]string{"browser": "down", "broker": "ok"},
+		},
+		{
+			name:     "stopping",
+			setup:    func(h *Health) { h.Ready("broker", ok); h.Started(); h.Stopping() },
+			healthz:  http.StatusOK,
+			readyz:   http.StatusServiceUnavailable,
+			checks:   map[string]string{"broker": "ok"},
+			stopping: true,
+		},
+		{
+			name:    "unwritable dir",
+			setup:   func(h *Health) { h.Live("state", Writable("/nonexistent/dir")); h.Started() },
+			healthz: http.StatusServiceUnavailable,
+			readyz:  http.StatusServiceUnavailable,
+		},
+		{
+			name:    "writable dir",
+			setup:   func(h *Health) { h.Live("state", Writable(t.TempDir())); h.Started() },
+			healthz: http.StatusOK,
+			readyz:  http.StatusOK,
+			checks:  map[string]string{"state": "ok"},
+		},
+	}
+	for _, tt := range tests {
+		h := New()
+		tt.setup(h)
+		mux := http.NewServeMux()
+		h.Register(mux)
+		server := httptest.NewServer(mux)
+
+		for path, want := range map[string]int{"/healthz": tt.healthz, "/readyz": tt.readyz} {
+			resp, err := http.Get(server.URL + path)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			var report Report
+			err = json.NewDecoder(resp.Body).Decode(&report)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("%s %s: %v", tt.name, path, err)
+			}
+			if resp.StatusCode != want {
+				t.Errorf("%s %s: status %d, want %d", tt.name, path, resp.StatusCode, want)
+			}
+			if status := map[bool]string{true: "ok", false: "unavailable"}[want == http.StatusOK]; report.Status != status {
+				t.Errorf("%s %s: report status %q, want %q", tt.name, path, report.Status, status)
+			}
+			if report.Starting != tt.starting || report.Stopping != tt.stopping {
+				t.Errorf("%s %s: starting %v stopping %v, want %v %v", tt.name, path, report.Starting, report.Stopping, tt.starting, tt.stopping)
+			}
+			for name, result := range tt.checks {
+				if report.Checks[name] != result {
+					t.Errorf("%s %s: check %s %q, want %q", tt.name, path, name, report.Checks[name], result)
+				}
+			}
+		}
+		server.Close()
+	}
+}
+
+func TestItemDone(t *testing.T) {
+	h := New()
+	h.Started()
+	if report, _, _ := h.run(context.Background()); report.LastItem != nil {
+		t.Fatalf("last item %v before any item was done", report.LastItem)
+	}
+	h.ItemDone()
+	report, live, ready := h.run(context.Background())
+	if report.LastItem == nil || !live || !ready {
+		t.Errorf("after an item: last item %v, live %v, ready %v", report.LastItem, live, ready)
+	}
+}
@@ -0,0 +1,116 @@
+// Package journal keeps the latest record per key in an append-only JSON Lines file. The
+// file is compacted to one line per key whenever it is opened.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// maxLine bounds the length of a single record.
+const maxLine = 16 * 1024 * 1024
+
+// Journal appends records of type V to its file. It is not safe for concurrent use, callers
+// guard it together with the map it mirrors.
+type Journal[V any] struct {
+	file *os.File
+}
+
+// Open loads the file at path, creating it when missing, and returns the last record of
+// every key that keep accepts. A nil keep accepts every record.
+func Open[V any](path string, key func(V) string, keep func(V) bool) (*Journal[V], map[string]V, error) {
+	records, err := load(path, key, keep)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := compact(path, records); err != nil {
+		return nil, nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Journal[V]{file: file}, records, nil
+}
+
+// Read loads the file at path like Open but neither compacts nor keeps it open, so it can
+// read a journal another process is appending to. A missing file holds no records.
+func Read[V any](path string, key func(V) string, keep func(V) bool) (map[string]V, error) {
+	return load(path, key, keep)
+}
+
+func load[V any](path string, key func(V) string, keep func(V) bool) (map[string]V, error) {
+	records := make(map[string]V)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLine)
+	for scanner.Scan() {
+		var v V
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			// a torn last line after a crash, everything before it is still good
+			continue
+		}
+		if keep == nil || keep(v) {
+			records[key(v)] = v
+		}
+	}
+	return records, scanner.Err()
+}
+
+func compact[V any](path string, records map[string]V) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, v := range records {
+		line, err := json.Marshal(v)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		_, _ = w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Append writes v as the latest record of its key.
+func (j *Journal[V]) Append(v V) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append to %s: %w", j.file.Name(), err)
+	}
+	return nil
+}
+
+// Truncate drops every record.
+func (j *Journal[V]) Truncate() error {
+	return j.file.Truncate(0)
+}
+
+// Close closes the file, records already appended stay on disk.
+func (j *Journal[V]) Close() error {
+	return j.file.Close()
+}
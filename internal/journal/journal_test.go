@@ -0,0 +1,112 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type record struct {
+	Key   string `json:"key"`
+	Value int    `json:"value"`
+}
+
+func key(r record) string { return r.Key }
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string // file content, "-" for no file
+		keep    func(record) bool
+		want    map[string]int
+	}{
+		{"missing file", "-", nil, map[string]int{}},
+		{"empty file", "", nil, map[string]int{}},
+		{"last record wins", "{\"key\":\"a\",\"value\":1}\n{\"key\":\"b\",\"value\":2}\n{\"key\":\"a\",\"value\":3}\n", nil, map[string]int{"a": 3, "b": 2}},
+		{"torn last line", "{\"key\":\"a\",\"value\":1}\n{\"key\":\"a\",\"val", nil, map[string]int{"a": 1}},
+		{"corrupted line", "{\"key\":\"a\",\"value\":1}\nnot json\n{\"key\":\"b\",\"value\":2}\n", nil, map[string]int{"a": 1, "b": 2}},
+		{"keep", "{\"key\":\"a\",\"value\":1}\n{\"key\":\"b\",\"value\":2}\n", func(r record) bool { return r.Value > 1 }, map[string]int{"b": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "journal.jsonl")
+			if tt.content != "-" {
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			read, err := Read(path, key, tt.keep)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			checkRecords(t, "Read()", read, tt.want)
+			if _, err := os.Stat(path); tt.content == "-" && err == nil {
+				t.Error("Read() created the missing file")
+			}
+
+			j, records, err := Open(path, key, tt.keep)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer j.Close()
+			checkRecords(t, "Open()", records, tt.want)
+
+			// Open compacts the file to one line per kept record
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lines := strings.Count(string(data), "\n"); lines != len(tt.want) {
+				t.Errorf("compacted file has %d lines, want %d:\n%s", lines, len(tt.want), data)
+			}
+		})
+	}
+}
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, _, err := Open(path, key, nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for _, r := range []record{{"a", 1}, {"b", 2}, {"a", 3}} {
+		if err := j.Append(r); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	records, err := Read(path, key, nil)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	checkRecords(t, "Read() after Append()", records, map[string]int{"a": 3, "b": 2})
+
+	if err := j.Truncate(); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	if err := j.Append(record{"c", 4}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	_, records, err = Open(path, key, nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	checkRecords(t, "Open() after Truncate()", records, map[string]int{"c": 4})
+}
+
+func checkRecords(t *testing.T, name string, got map[string]record, want map[string]int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+		return
+	}
+	for k, v := range want {
+		if got[k].Value != v {
+			t.Errorf("%s[%q] = %v, want %d", name, k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Package logging configures log/slog for the pipeline stages and carries a logger with the
+// correlation fields of the work at hand through contexts, so one item can be followed
+// through discovery and detail by its fields.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Correlation fields, the same item carries the same values in both stages.
+const (
+	KeyStage     = "stage"
+	KeyRunID     = "run_id"
+	KeyMood      = "mood"
+	KeyPage      = "page"
+	KeyPageURL   = "page_url"
+	KeyItemURL   = "item_url"
+	KeyWorker    = "worker"
+	KeyMessageID = "message_id"
+)
+
+// Setup installs the default logger for stage, configured by LOG_LEVEL (debug, info, warn
+// or error, info by default) and LOG_FORMAT (text or json, text by default). Lines still
+// written through the log package end up in the same handler.
+func Setup(stage string) error {
+	var level slog.Level
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := level.UnmarshalText([]byte(v)); err != nil {
+			return fmt.Errorf("invalid LOG_LEVEL %q: %w", v, err)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch format := strings.ToLower(os.Getenv("LOG_FORMAT")); format {
+	case "", "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("invalid LOG_FORMAT %q", format)
+	}
+	slog.SetDefault(slog.New(handler).With(KeyStage, stage))
+	return nil
+}
+
+// Fatal logs msg at error level and exits, the slog counterpart of log.Fatal.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type loggerKey struct{}
+
+// With returns a context whose logger carries args as additional fields.
+func With(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, loggerKey{}, From(ctx).With(args...))
+}
+
+// From returns the logger of ctx, the default logger when ctx has none.
+func From(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
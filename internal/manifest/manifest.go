@@ -0,0 +1,239 @@
+// Package manifest records what a crawl run did: when it ran, what it found per mood and
+// what went wrong. Manifests of two runs are compared by the diff command.
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Manifest describes a single run of a pipeline stage.
+type Manifest struct {
+	RunID      string                `json:"run_id"`
+	Stage      string                `json:"stage"`
+	Full       bool                  `json:"full"`
+	StartedAt  time.Time             `json:"started_at"`
+	FinishedAt time.Time             `json:"finished_at"`
+	Moods      map[string]*MoodStats `json:"moods"`
+	Items      []Item                `json:"items"`
+	Errors     []Error               `json:"errors"`
+	// Interrupted is set when a shutdown cut the run short: discovery did not get through
+	// every page, or detail abandoned items in flight.
+	Interrupted bool `json:"interrupted,omitempty"`
+	// Pages summarizes the item cards of every listing page, Cards lists the cards that
+	// could not be read completely.
+	Pages []PageSummary `json:"pages,omitempty"`
+	Cards []CardIssue   `json:"cards,omitempty"`
+
+	mu sync.Mutex
+}
+
+// MoodStats counts what happened to the items of one mood.
+type MoodStats struct {
+	Pages     int `json:"pages"`
+	Items     int `json:"items"`
+	Processed int `json:"processed"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+}
+
+// Item is an item seen during the run with the hash of its content.
+type Item struct {
+	Mood   string `json:"mood"`
+	URL    string `json:"url"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Artist string `json:"artist"`
+	Genre  string `json:"genre"`
+	Hash   string `json:"hash"`
+}
+
+// PageSummary counts the item cards of a listing page by how well they could be read.
+type PageSummary struct {
+	Mood        string `json:"mood"`
+	Page        int    `json:"page"`
+	URL         string `json:"url"`
+	Cards       int    `json:"cards"`
+	Extracted   int    `json:"extracted"`
+	Incomplete  int    `json:"incomplete"`
+	Quarantined int    `json:"quarantined"`
+}
+
+// CardIssue is an item card that could not be read completely. Quarantined cards were not
+// passed on and keep their markup for inspection.
+type CardIssue struct {
+	Mood        string   `json:"mood"`
+	Page        int      `json:"page"`
+	PageURL     string   `json:"page_url"`
+	Card        int      `json:"card"`
+	ItemURL     string   `json:"item_url,omitempty"`
+	Errors      []string `json:"errors"`
+	Quarantined bool     `json:"quarantined"`
+	HTML        string   `json:"html,omitempty"`
+}
+
+type Error struct {
+	At      time.Time `json:"at"`
+	Mood    string    `json:"mood,omitempty"`
+	URL     string    `json:"url,omitempty"`
+	Message string    `json:"message"`
+}
+
+// New starts the manifest of a run.
+func New(runID, stage string, full bool) *Manifest {
+	return &Manifest{
+		RunID:     runID,
+		Stage:     stage,
+		Full:      full,
+		StartedAt: time.Now().UTC(),
+		Moods:     make(map[string]*MoodStats),
+	}
+}
+
+// Update applies fn to the stats of mood.
+func (m *Manifest) Update(mood string, fn func(*MoodStats)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats, ok := m.Moods[mood]
+	if !ok {
+		stats = &MoodStats{}
+		m.Moods[mood] = stats
+	}
+	fn(stats)
+}
+
+// AddItem records an item seen in mood.
+func (m *Manifest) AddItem(item Item) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Items = append(m.Items, item)
+}
+
+// AddError records a failure, mood and url may be empty.
+func (m *Manifest) AddError(mood, url string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Errors = append(m.Errors, Error{At: time.Now().UTC(), Mood: mood, URL: url, Message: err.Error()})
+}
+
+// AddPage records the card summary of a listing page.
+func (m *Manifest) AddPage(page PageSummary) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Pages = append(m.Pages, page)
+}
+
+// AddCardIssue records an item card that could not be read completely.
+func (m *Manifest) AddCardIssue(issue CardIssue) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Cards = append(m.Cards, issue)
+}
+
+// Write finishes the manifest and writes it to dir as <stage>-<run id>.json.
+func (m *Manifest) Write(dir string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.FinishedAt = time.Now().UTC()
+	sort.Slice(m.Items, func(i, j int) bool {
+		if m.Items[i].Mood != m.Items[j].Mood {
+			return m.Items[i].Mood < m.Items[j].Mood
+		}
+		return m.Items[i].URL < m.Items[j].URL
+	})
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create manifest dir: %w", err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, m.Stage+"-"+m.RunID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return path, os.Rename(tmp, path)
+}
+
+// Runs holds a manifest per crawl run for a long running stage that processes the messages
+// of several runs. A run picks up the manifest an earlier process left in dir, so restarts
+// add to the same manifest.
+type Runs struct {
+	dir   string
+	stage string
+	full  bool
+
+	mu   sync.Mutex
+	runs map[string]*Manifest
+}
+
+// NewRuns keeps the manifests of stage in dir.
+func NewRuns(dir, stage string, full bool) *Runs {
+	return &Runs{dir: dir, stage: stage, full: full, runs: make(map[string]*Manifest)}
+}
+
+// Get returns the manifest of runID, an empty runID stands for messages of unknown runs.
+func (r *Runs) Get(runID string) *Manifest {
+	if runID == "" {
+		runID = "unknown"
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.runs[runID]; ok {
+		return m
+	}
+	m, err := Read(filepath.Join(r.dir, r.stage+"-"+runID+".json"))
+	if err != nil {
+		m = New(runID, r.stage, r.full)
+	}
+	r.runs[runID] = m
+	return m
+}
+
+// Write writes every manifest and returns their paths. interrupted marks every manifest as
+// cut short, a mark set by this or an earlier process is never cleared.
+func (r *Runs) Write(interrupted bool) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var paths []string
+	var errs []error
+	for _, m := range r.runs {
+		if interrupted {
+			m.mu.Lock()
+			m.Interrupted = true
+			m.mu.Unlock()
+		}
+		path, err := m.Write(r.dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, errors.Join(errs...)
+}
+
+// Read loads the manifest at path.
+func Read(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if m.Moods == nil {
+		m.Moods = make(map[string]*MoodStats)
+	}
+	return &m, nil
+}
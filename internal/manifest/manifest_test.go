@@ -0,0 +1,69 @@
+package manifest
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRuns(t *testing.T) {
+	dir := t.TempDir()
+	runs := NewRuns(dir, "detail", false)
+	first := runs.Get("run-1")
+	if runs.Get("run-1") != first {
+		t.Fatal("Get returned another manifest for the same run")
+	}
+	if m := runs.Get(""); m.RunID != "unknown" {
+		t.Fatalf("empty run id got run %q, want unknown", m.RunID)
+	}
+	first.AddItem(Item{Mood: "m", URL: "https://songsara.net/item/a/"})
+	first.Update("m", func(stats *MoodStats) { stats.Items++ })
+	first.AddError("m", "https://songsara.net/item/b/", errors.New("failed"))
+
+	paths, err := runs.Write(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "detail-run-1.json"), filepath.Join(dir, "detail-unknown.json")}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Fatalf("Write wrote %v, want %v", paths, want)
+	}
+
+	// a restarted process adds to the manifest the earlier one left behind
+	restarted := NewRuns(dir, "detail", false)
+	m := restarted.Get("run-1")
+	if len(m.Items) != 1 || len(m.Errors) != 1 || m.Moods["m"] == nil || m.Moods["m"].Items != 1 {
+		t.Fatalf("restart did not reload the manifest: %+v", m)
+	}
+	m.AddItem(Item{Mood: "m", URL: "https://songsara.net/item/c/"})
+
+	tests := []struct {
+		name        string
+		interrupted bool
+		want        bool
+	}{
+		{name: "periodic write", interrupted: false, want: false},
+		{name: "interrupted", interrupted: true, want: true},
+		{name: "periodic write after interruption", interrupted: false, want: true},
+	}
+	for _, tt := range tests {
+		if _, err := restarted.Write(tt.interrupted); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		written, err := Read(filepath.Join(dir, "detail-run-1.json"))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if written.Interrupted != tt.want {
+			t.Errorf("%s: interrupted %v, want %v", tt.name, written.Interrupted, tt.want)
+		}
+		if len(written.Items) != 2 {
+			t.Errorf("%s: %d items, want 2", tt.name, len(written.Items))
+		}
+	}
+
+	// the mark survives a restart as well
+	if m := NewRuns(dir, "detail", false).Get("run-1"); !m.Interrupted {
+		t.Error("restart cleared the interruption")
+	}
+}
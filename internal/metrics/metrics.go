@@ -0,0 +1,124 @@
+// Package metrics defines the Prometheus metrics of both pipeline stages and serves them
+// on /metrics. Metrics live in the default registry, so a stage exposes the metrics of the
+// packages it uses without wiring them up.
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "songsc"
+
+// Results of an extraction and of a page fetch.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Outcomes of an item, in both stages.
+const (
+	ItemProcessed = "processed"
+	ItemUnchanged = "unchanged"
+	ItemFailed    = "failed"
+)
+
+// How a delivery was settled.
+const (
+	DeliveryAcked        = "acked"
+	DeliveryNacked       = "nacked"
+	DeliveryRetried      = "retried"
+	DeliveryDeadLettered = "dead_lettered"
+)
+
+var (
+	MoodsDiscovered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "discover_moods_total",
+		Help:      "Moods found on the moods page.",
+	})
+	PagesDiscovered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "discover_pages_total",
+		Help:      "Listing pages fetched, by mood.",
+	}, []string{"mood"})
+	DiscoveredItems = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "discover_items_total",
+		Help:      "Items found on listing pages by mood and outcome, processed items were published.",
+	}, []string{"mood", "result"})
+
+	DeliveriesConsumed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deliveries_consumed_total",
+		Help:      "Deliveries taken from the items queue.",
+	})
+	DeliveriesSettled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deliveries_settled_total",
+		Help:      "Deliveries of the items queue by how they were settled.",
+	}, []string{"outcome"})
+	QueueLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "queue_lag_seconds",
+		Help:      "Time from publishing an item to the detail stage consuming it.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+	})
+	DetailItems = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "detail_items_total",
+		Help:      "Items handled by the detail stage by mood and outcome.",
+	}, []string{"mood", "result"})
+
+	Extractions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "extractions_total",
+		Help:      "Runs of every extractor by result, a rising failure rate usually means a selector broke.",
+	}, []string{"extractor", "result"})
+	FetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "page_fetch_duration_seconds",
+		Help:      "Latency of page fetches by fetcher and result.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"fetcher", "result"})
+	BytesWritten = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sink_bytes_written_total",
+		Help:      "Bytes of catalog records written by the file based sinks.",
+	}, []string{"sink"})
+)
+
+// Extracted counts a run of extractor, failed when err is not nil.
+func Extracted(extractor string, err error) {
+	Extractions.WithLabelValues(extractor, result(err)).Inc()
+}
+
+// ObserveFetch records how long fetching a page with fetcher took since start.
+func ObserveFetch(fetcher string, start time.Time, err error) {
+	FetchDuration.WithLabelValues(fetcher, result(err)).Observe(time.Since(start).Seconds())
+}
+
+func result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
+
+// Serve adds /metrics to mux and serves mux on addr for as long as the process runs. It does
+// not block, a server that cannot listen is logged and the stage keeps running without it.
+func Serve(addr string, mux *http.ServeMux) {
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		slog.Info("Serving metrics and health checks", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil {
+			slog.Error("could not serve metrics and health checks", "addr", addr, "error", err)
+		}
+	}()
+}
@@ -0,0 +1,84 @@
+package model
+
+// Catalog records carry the IDs of the records they refer to next to their names, see
+// EntityID. Names are for display, joins should use the IDs.
+
+type Album struct {
+	ID            string        `json:"id"`
+	Name          string        `json:"name"`
+	Artists       []string      `json:"artists"`
+	ArtistIDs     []string      `json:"artist_ids"`
+	Type          string        `json:"type"`
+	Genres        []string      `json:"genres"`
+	GenreIDs      []string      `json:"genre_ids"`
+	Moods         []string      `json:"moods"`
+	MoodIDs       []string      `json:"mood_ids"`
+	Instruments   []string      `json:"instruments"`
+	InstrumentIDs []string      `json:"instrument_ids"`
+	Publisher     string        `json:"publisher"`
+	PublisherID   string        `json:"publisher_id"`
+	Image         string        `json:"img"`
+	Tracks        []AlbumTracks `json:"tracks"`
+}
+
+type AlbumTracks struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Info     string `json:"info"`
+	Duration string `json:"duration"`
+	MP3Link  string `json:"mp3_link"`
+}
+
+type Track struct {
+	ID            string   `json:"id"`
+	ItemID        string   `json:"item_id"`
+	Title         string   `json:"name"`
+	Artist        string   `json:"artist"`
+	ArtistIDs     []string `json:"artist_ids"`
+	Album         string   `json:"album"`
+	Type          string   `json:"type"`
+	Genres        []string `json:"genres"`
+	GenreIDs      []string `json:"genre_ids"`
+	Moods         []string `json:"moods"`
+	MoodIDs       []string `json:"mood_ids"`
+	Instruments   []string `json:"instruments"`
+	InstrumentIDs []string `json:"instrument_ids"`
+	Publisher     string   `json:"publisher"`
+	PublisherID   string   `json:"publisher_id"`
+	Info          string   `json:"info"`
+	Image         string   `json:"img"`
+	Duration      string   `json:"duration"`
+	MP3Link       string   `json:"mp3_link"`
+}
+
+type Artist struct {
+	ID          string `json:"id"`
+	NameEN      string `json:"name_en"`
+	NameFA      string `json:"name_fa"`
+	Description string `json:"description"`
+	Img         string `json:"img"`
+}
+
+type Instrument struct {
+	ID          string `json:"id"`
+	NameEN      string `json:"name_en"`
+	NameFA      string `json:"name_fa"`
+	Description string `json:"description"`
+}
+
+type Genre struct {
+	ID     string `json:"id"`
+	NameEN string `json:"name_en"`
+	NameFA string `json:"name_fa"`
+}
+
+type Publisher struct {
+	ID     string `json:"id"`
+	NameEN string `json:"name_en"`
+}
+
+type MoodData struct {
+	ID     string `json:"id"`
+	NameFA string `json:"name_fa"`
+	NameEN string `json:"name_en"`
+}
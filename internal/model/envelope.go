@@ -0,0 +1,29 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// SchemaVersion is the version of the envelope written by this build.
+const SchemaVersion = 1
+
+// Envelope wraps every queue message with the metadata needed to trace it back to the
+// crawl run and page it came from.
+type Envelope[T any] struct {
+	SchemaVersion int       `json:"schema_version"`
+	MessageID     string    `json:"message_id"`
+	RunID         string    `json:"run_id"`
+	ProducedAt    time.Time `json:"produced_at"`
+	SourceURL     string    `json:"source_url"`
+	Page          int       `json:"page"`
+	Payload       T         `json:"payload"`
+}
+
+// NewID returns a random 128 bit identifier in hex.
+func NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+const (
+	KindItem       = "item"
+	KindAlbum      = "album"
+	KindTrack      = "track"
+	KindArtist     = "artist"
+	KindInstrument = "instrument"
+	KindGenre      = "genre"
+	KindMood       = "mood"
+	KindPublisher  = "publisher"
+)
+
+// EntityID derives the ID of a catalog record of the given kind from the URL or slug that
+// identifies it on the site. Only the path of a URL is used, so the ID survives a change of
+// host and the records of a mirror join with those of the real site.
+func EntityID(kind, ref string) string {
+	key := CanonicalURL(ref)
+	if u, err := url.Parse(key); err == nil && u.Host != "" {
+		key = u.Path
+	} else {
+		key = strings.ToLower(key)
+	}
+	sum := sha256.Sum256([]byte(kind + " " + key))
+	return kind + "-" + hex.EncodeToString(sum[:8])
+}
+
+// CanonicalURL normalizes a URL for use as a key: lower-case scheme and host, no query,
+// fragment or trailing slash. Values that are not URLs are returned trimmed.
+func CanonicalURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	canonical := url.URL{
+		Scheme: strings.ToLower(u.Scheme),
+		Host:   strings.ToLower(u.Host),
+		Path:   strings.TrimSuffix(u.Path, "/"),
+	}
+	return canonical.String()
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://SongSara.net/artist/a/", "https://songsara.net/artist/a"},
+		{"HTTPS://songsara.net/artist/a?utm=x#top", "https://songsara.net/artist/a"},
+		{"  https://songsara.net/artist/a  ", "https://songsara.net/artist/a"},
+		{"https://songsara.net/Artist/A", "https://songsara.net/Artist/A"},
+		{"  Publisher 1 ", "Publisher 1"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := CanonicalURL(tt.in); got != tt.want {
+			t.Errorf("CanonicalURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEntityID(t *testing.T) {
+	id := EntityID(KindArtist, "https://songsara.net/artist/a/")
+	if !strings.HasPrefix(id, KindArtist+"-") || len(id) != len(KindArtist)+1+16 {
+		t.Fatalf("EntityID() = %q, want %s- followed by 16 hex digits", id, KindArtist)
+	}
+
+	same := []string{
+		"https://songsara.net/artist/a",
+		"http://mirror.local:8080/artist/a/",
+		"https://SONGSARA.net/artist/a?x=1",
+	}
+	for _, ref := range same {
+		if got := EntityID(KindArtist, ref); got != id {
+			t.Errorf("EntityID(%q) = %q, want %q", ref, got, id)
+		}
+	}
+
+	different := []struct {
+		kind, ref string
+	}{
+		{KindInstrument, "https://songsara.net/artist/a/"},
+		{KindArtist, "https://songsara.net/artist/b/"},
+	}
+	for _, tt := range different {
+		if got := EntityID(tt.kind, tt.ref); got == id {
+			t.Errorf("EntityID(%q, %q) = %q, want it to differ", tt.kind, tt.ref, got)
+		}
+	}
+
+	if EntityID(KindPublisher, "Publisher 1") != EntityID(KindPublisher, " publisher 1") {
+		t.Error("EntityID of a name depends on case or surrounding space")
+	}
+}
@@ -0,0 +1,33 @@
+// Package model defines the data shared by the pipeline stages: the messages exchanged
+// over RabbitMQ and the catalog records written by the detail stage.
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Item is a single listing card found by the discovery stage.
+type Item struct {
+	Name       string `json:"name"`
+	ArtistName string `json:"artist_name"`
+	Type       string `json:"type"`
+	Genre      string `json:"genre"`
+	Date       string `json:"date"`
+	ItemURL    string `json:"item_url"`
+	ImageURL   string `json:"image_url"`
+}
+
+// ItemMessage is published by discovery for every item of a mood listing and consumed by
+// the detail stage.
+type ItemMessage struct {
+	Mood string `json:"mood"`
+	Item Item   `json:"item"`
+}
+
+// ItemKey identifies an item by its URL, it is used as the message ID of ItemMessages so
+// redeliveries and republishing of the same item share one key.
+func ItemKey(itemURL string) string {
+	sum := sha256.Sum256([]byte(itemURL))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,195 @@
+package page
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	FixtureRecord = "record"
+	FixtureReplay = "replay"
+)
+
+var ErrNotRecorded = errors.New("page is not in the fixture archive")
+
+type Config struct {
+	Kind        string
+	FixtureMode string
+	FixtureDir  string
+	Instance    int
+}
+
+// ConfigFromEnv reads FETCHER, FIXTURE_MODE and FIXTURE_DIR.
+func ConfigFromEnv() Config {
+	return Config{
+		Kind:        os.Getenv("FETCHER"),
+		FixtureMode: os.Getenv("FIXTURE_MODE"),
+		FixtureDir:  os.Getenv("FIXTURE_DIR"),
+	}
+}
+
+// Open builds the fetcher of the configured kind and, depending on the fixture mode,
+// records every fetched page into the fixture dir or serves pages exclusively from it.
+// The latency of every fetch is recorded in the page fetch metrics.
+func Open(cfg Config) (Fetcher, error) {
+	fetcher, err := open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	label := cfg.Kind
+	switch {
+	case cfg.FixtureMode == FixtureReplay:
+		label = FixtureReplay
+	case label == "":
+		label = "selenium"
+	}
+	return &instrumented{Fetcher: fetcher, label: label}, nil
+}
+
+func open(cfg Config) (Fetcher, error) {
+	switch cfg.FixtureMode {
+	case "":
+		return New(cfg.Kind, cfg.Instance)
+	case FixtureReplay:
+		return NewReplayer(Archive(cfg.FixtureDir)), nil
+	case FixtureRecord:
+		if err := os.MkdirAll(cfg.FixtureDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create fixture dir: %w", err)
+		}
+		fetcher, err := New(cfg.Kind, cfg.Instance)
+		if err != nil {
+			return nil, err
+		}
+		return NewRecorder(fetcher, Archive(cfg.FixtureDir)), nil
+	default:
+		return nil, fmt.Errorf("unknown fixture mode %q", cfg.FixtureMode)
+	}
+}
+
+// Archive is a directory of recorded pages. Every page is stored as <key>.html with a
+// <key>.json metadata file next to it, key being the hash of the requested URL.
+type Archive string
+
+type fixtureMeta struct {
+	URL        string    `json:"url"`
+	FinalURL   string    `json:"final_url"`
+	StatusCode int       `json:"status_code"`
+	FetchedAt  time.Time `json:"fetched_at"`
+}
+
+func (a Archive) key(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:12])
+}
+
+func (a Archive) Put(url string, doc *Document, statusCode int) error {
+	meta := fixtureMeta{URL: url, FinalURL: url, StatusCode: statusCode, FetchedAt: time.Now().UTC()}
+	var body []byte
+	if doc != nil {
+		meta.FinalURL = doc.URL
+		body = doc.Body
+	}
+	metaBytes, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	base := filepath.Join(string(a), a.key(url))
+	if err := os.WriteFile(base+".html", body, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(base+".json", metaBytes, 0644)
+}
+
+func (a Archive) Get(url string) (*Document, error) {
+	base := filepath.Join(string(a), a.key(url))
+	metaBytes, err := os.ReadFile(base + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotRecorded, url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var meta fixtureMeta
+	if err := json.Unmarshal(metaBytes, &meta); err != nil {
+		return nil, fmt.Errorf("corrupted fixture for %s: %w", url, err)
+	}
+	if meta.StatusCode < 200 || meta.StatusCode > 299 {
+		return nil, &StatusError{URL: url, StatusCode: meta.StatusCode}
+	}
+	body, err := os.ReadFile(base + ".html")
+	if err != nil {
+		return nil, err
+	}
+	return Parse(meta.FinalURL, body)
+}
+
+// Recorder fetches pages with the wrapped fetcher and stores them in an Archive.
+type Recorder struct {
+	fetcher Fetcher
+	archive Archive
+}
+
+func NewRecorder(fetcher Fetcher, archive Archive) *Recorder {
+	return &Recorder{fetcher: fetcher, archive: archive}
+}
+
+func (r *Recorder) Fetch(ctx context.Context, url string) (*Document, error) {
+	doc, err := r.fetcher.Fetch(ctx, url)
+	var statusErr *StatusError
+	switch {
+	case errors.As(err, &statusErr):
+		if putErr := r.archive.Put(url, nil, statusErr.StatusCode); putErr != nil {
+			return nil, fmt.Errorf("failed to record %s: %w", url, putErr)
+		}
+		return nil, err
+	case err != nil:
+		return nil, err
+	}
+	if err := r.archive.Put(url, doc, 200); err != nil {
+		return nil, fmt.Errorf("failed to record %s: %w", url, err)
+	}
+	return doc, nil
+}
+
+func (r *Recorder) Ping(ctx context.Context) error {
+	return r.fetcher.Ping(ctx)
+}
+
+func (r *Recorder) Close() error {
+	return r.fetcher.Close()
+}
+
+// Replayer serves pages from an Archive and never touches the network.
+type Replayer struct {
+	archive Archive
+}
+
+func NewReplayer(archive Archive) *Replayer {
+	return &Replayer{archive: archive}
+}
+
+func (r *Replayer) Fetch(ctx context.Context, url string) (*Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.archive.Get(url)
+}
+
+// Ping checks that the archive is there.
+func (r *Replayer) Ping(ctx context.Context) error {
+	if _, err := os.Stat(string(r.archive)); err != nil {
+		return fmt.Errorf("fixture archive is not readable: %w", err)
+	}
+	return nil
+}
+
+func (r *Replayer) Close() error {
+	return nil
+}
@@ -0,0 +1,83 @@
+package page
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/item/a/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><h1 class="title">Item A</h1></body></html>`))
+	})
+	mux.HandleFunc("/old/a/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/item/a/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/missing/", http.NotFound)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	archive := Archive(t.TempDir())
+	recorder := NewRecorder(NewHTTPFetcher(), archive)
+	replayer := NewReplayer(archive)
+
+	tests := []struct {
+		name     string
+		path     string
+		record   bool
+		title    string
+		finalURL string
+		status   int
+		err      error
+	}{
+		{name: "page", path: "/item/a/", record: true, title: "Item A", finalURL: "/item/a/"},
+		{name: "redirect", path: "/old/a/", record: true, title: "Item A", finalURL: "/item/a/"},
+		{name: "not found", path: "/missing/", record: true, status: http.StatusNotFound},
+		{name: "not recorded", path: "/item/b/", err: ErrNotRecorded},
+	}
+	for _, tt := range tests {
+		url := server.URL + tt.path
+		if tt.record {
+			_, err := recorder.Fetch(context.Background(), url)
+			if (err != nil) != (tt.status != 0) {
+				t.Fatalf("%s: record: %v", tt.name, err)
+			}
+		}
+
+		// replayed pages come from the archive alone
+		doc, err := replayer.Fetch(context.Background(), url)
+		var statusErr *StatusError
+		switch {
+		case tt.err != nil:
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: error %v, want %v", tt.name, err, tt.err)
+			}
+		case tt.status != 0:
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.status || statusErr.URL != url {
+				t.Errorf("%s: error %v, want status %d for %s", tt.name, err, tt.status, url)
+			}
+		case err != nil:
+			t.Errorf("%s: replay: %v", tt.name, err)
+		default:
+			if doc.URL != server.URL+tt.finalURL {
+				t.Errorf("%s: url %s, want %s", tt.name, doc.URL, server.URL+tt.finalURL)
+			}
+			title, err := doc.FindElement(ByClassName, "title")
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if text, _ := title.Text(); text != tt.title {
+				t.Errorf("%s: title %q, want %q", tt.name, text, tt.title)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := replayer.Fetch(ctx, server.URL+"/item/a/"); !errors.Is(err, context.Canceled) {
+		t.Errorf("replay with a cancelled context: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package page
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const userAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36"
+
+// StatusError is returned when the server answers with a non-2xx status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d for %s", e.StatusCode, e.URL)
+}
+
+// HTTPFetcher downloads pages with net/http, without running any javascript.
+type HTTPFetcher struct {
+	client *http.Client
+}
+
+func NewHTTPFetcher() *HTTPFetcher {
+	return &HTTPFetcher{client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (*Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	return Parse(resp.Request.URL.String(), body)
+}
+
+// Ping always succeeds, an http.Client has nothing that could die.
+func (f *HTTPFetcher) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (f *HTTPFetcher) Close() error {
+	f.client.CloseIdleConnections()
+	return nil
+}
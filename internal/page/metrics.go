@@ -0,0 +1,21 @@
+package page
+
+import (
+	"context"
+	"time"
+
+	"song-sc/internal/metrics"
+)
+
+// instrumented times every fetch of the wrapped fetcher.
+type instrumented struct {
+	Fetcher
+	label string
+}
+
+func (f *instrumented) Fetch(ctx context.Context, url string) (*Document, error) {
+	start := time.Now()
+	doc, err := f.Fetcher.Fetch(ctx, url)
+	metrics.ObserveFetch(f.label, start, err)
+	return doc, err
+}
@@ -0,0 +1,171 @@
+package page
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+const (
+	ByID          = "id"
+	ByTagName     = "tag name"
+	ByClassName   = "class name"
+	ByCSSSelector = "css selector"
+)
+
+var ErrNoSuchElement = errors.New("no such element")
+
+// Fetcher loads a page and returns a parsed snapshot of it.
+// Implementations decide how the page is rendered (a real browser, plain HTTP, ...),
+// extractors only ever see the resulting Document. Fetch gives up once ctx is done.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (*Document, error)
+	// Ping reports whether the fetcher can still fetch, without fetching anything.
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// New returns the fetcher registered under kind. Fetchers running side by side in one
+// process need distinct instance numbers, each Selenium instance gets its own ports.
+func New(kind string, instance int) (Fetcher, error) {
+	switch kind {
+	case "", "selenium":
+		return NewSeleniumFetcher("./chromedriver", 4444+instance, 9222+instance)
+	case "http":
+		return NewHTTPFetcher(), nil
+	default:
+		return nil, fmt.Errorf("unknown fetcher %q", kind)
+	}
+}
+
+type Document struct {
+	*Element
+	URL  string
+	Body []byte
+}
+
+func Parse(pageURL string, body []byte) (*Document, error) {
+	root, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", pageURL, err)
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page url %s: %w", pageURL, err)
+	}
+	return &Document{
+		Element: &Element{node: root, base: base},
+		URL:     pageURL,
+		Body:    body,
+	}, nil
+}
+
+type Element struct {
+	node *html.Node
+	base *url.URL
+}
+
+func (e *Element) FindElement(by, value string) (*Element, error) {
+	sel, err := compile(by, value)
+	if err != nil {
+		return nil, err
+	}
+	n := cascadia.Query(e.node, sel)
+	if n == nil {
+		return nil, fmt.Errorf("%w: %s=%q", ErrNoSuchElement, by, value)
+	}
+	return &Element{node: n, base: e.base}, nil
+}
+
+func (e *Element) FindElements(by, value string) ([]*Element, error) {
+	sel, err := compile(by, value)
+	if err != nil {
+		return nil, err
+	}
+	nodes := cascadia.QueryAll(e.node, sel)
+	elements := make([]*Element, 0, len(nodes))
+	for _, n := range nodes {
+		elements = append(elements, &Element{node: n, base: e.base})
+	}
+	return elements, nil
+}
+
+// GetAttribute returns the value of the named attribute. Like a browser, href and src are
+// resolved against the page URL.
+func (e *Element) GetAttribute(name string) (string, error) {
+	for _, attr := range e.node.Attr {
+		if attr.Key != name {
+			continue
+		}
+		if name == "href" || name == "src" {
+			ref, err := url.Parse(strings.TrimSpace(attr.Val))
+			if err == nil {
+				return e.base.ResolveReference(ref).String(), nil
+			}
+		}
+		return attr.Val, nil
+	}
+	return "", fmt.Errorf("attribute %q not found on <%s>", name, e.node.Data)
+}
+
+// Text returns the text content of the element with whitespace collapsed.
+func (e *Element) Text() (string, error) {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+			return
+		}
+		if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style") {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(e.node)
+	return strings.Join(strings.Fields(sb.String()), " "), nil
+}
+
+func compile(by, value string) (cascadia.Sel, error) {
+	var css string
+	switch by {
+	case ByID:
+		css = "#" + value
+	case ByTagName:
+		css = value
+	case ByClassName:
+		css = "." + value
+	case ByCSSSelector:
+		css = value
+	default:
+		return nil, fmt.Errorf("unsupported locator %q", by)
+	}
+	sel, err := cascadia.Parse(css)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %s=%q: %w", by, value, err)
+	}
+	return sel, nil
+}
+
+// PageURL is the URL of the document the element belongs to.
+func (e *Element) PageURL() string {
+	return e.base.String()
+}
+
+// HTML renders the element and its children back to markup.
+func (e *Element) HTML() string {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, e.node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,91 @@
+package page
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/chrome"
+)
+
+// SeleniumFetcher renders pages in a headless Chrome and snapshots the resulting DOM.
+type SeleniumFetcher struct {
+	service *selenium.Service
+	driver  selenium.WebDriver
+}
+
+func NewSeleniumFetcher(chromeDriverPath string, port, debuggingPort int) (*SeleniumFetcher, error) {
+	service, err := selenium.NewChromeDriverService(chromeDriverPath, port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start chromedriver: %w", err)
+	}
+
+	caps := selenium.Capabilities{}
+	chromeCaps := chrome.Capabilities{
+		Args: []string{
+			"--headless",
+			"--no-sandbox",
+			"--disable-dev-shm-usage",
+			"--disable-gpu",
+			fmt.Sprintf("--remote-debugging-port=%d", debuggingPort),
+		},
+	}
+	caps.AddChrome(chromeCaps)
+
+	driver, err := selenium.NewRemote(caps, fmt.Sprintf("http://localhost:%d/wd/hub", port))
+	if err != nil {
+		_ = service.Stop()
+		return nil, fmt.Errorf("failed to open a browser session: %w", err)
+	}
+
+	return &SeleniumFetcher{service: service, driver: driver}, nil
+}
+
+// Fetch cannot interrupt a navigation the browser has started, ctx is checked before and
+// after it instead.
+func (f *SeleniumFetcher) Fetch(ctx context.Context, url string) (*Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to navigate to %s: %w", url, err)
+	}
+	if err := f.driver.Get(url); err != nil {
+		return nil, fmt.Errorf("failed to navigate to %s: %w", url, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read page source of %s: %w", url, err)
+	}
+	source, err := f.driver.PageSource()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page source of %s: %w", url, err)
+	}
+	current, err := f.driver.CurrentURL()
+	if err != nil {
+		current = url
+	}
+	return Parse(current, []byte(source))
+}
+
+// Ping asks chromedriver for its status, which it answers even while a page is loading.
+func (f *SeleniumFetcher) Ping(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := f.driver.Status()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("chromedriver is not responding: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("chromedriver is not responding: %w", ctx.Err())
+	}
+}
+
+func (f *SeleniumFetcher) Close() error {
+	err := f.driver.Quit()
+	if stopErr := f.service.Stop(); err == nil {
+		err = stopErr
+	}
+	return err
+}
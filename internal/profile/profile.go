@@ -0,0 +1,249 @@
+// Package profile holds the DOM contract of the scraped site: every selector and attribute
+// the extractors rely on, loaded from a versioned JSON profile at startup.
+package profile
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/andybalholm/cascadia"
+
+	"song-sc/internal/page"
+)
+
+const Version = 1
+
+//go:embed songsara.json
+var defaultProfile []byte
+
+type Profile struct {
+	Version    int                 `json:"version"`
+	Moods      MoodsSelectors      `json:"moods"`
+	Listing    ListingSelectors    `json:"listing"`
+	Item       ItemSelectors       `json:"item"`
+	Artist     ArtistSelectors     `json:"artist"`
+	Instrument InstrumentSelectors `json:"instrument"`
+	Attributes Attributes          `json:"attributes"`
+}
+
+type MoodsSelectors struct {
+	Container Selector `json:"container"`
+	Link      Selector `json:"link"`
+	Name      Selector `json:"name"`
+}
+
+type ListingSelectors struct {
+	Container      Selector `json:"container"`
+	Card           Selector `json:"card"`
+	Image          Selector `json:"image"`
+	Link           Selector `json:"link"`
+	Labels         Selector `json:"labels"`
+	TypeLabelIndex int      `json:"type_label_index"`
+	Type           Selector `json:"type"`
+	Details        Selector `json:"details"`
+	Detail         Selector `json:"detail"`
+	DetailFields   []string `json:"detail_fields"`
+	PageLinks      Selector `json:"page_links"`
+}
+
+type ItemSelectors struct {
+	Artists     Selector `json:"artists"`
+	Genres      Selector `json:"genres"`
+	Moods       Selector `json:"moods"`
+	Instruments Selector `json:"instruments"`
+	Publisher   Selector `json:"publisher"`
+	TaxonLink   Selector `json:"taxon_link"`
+	Player      Selector `json:"player"`
+	TrackList   Selector `json:"track_list"`
+	Track       Selector `json:"track"`
+}
+
+type ArtistSelectors struct {
+	ImageBox       Selector `json:"image_box"`
+	Image          Selector `json:"image"`
+	DescriptionBox Selector `json:"description_box"`
+	Description    Selector `json:"description"`
+}
+
+type InstrumentSelectors struct {
+	DescriptionBox Selector `json:"description_box"`
+	Description    Selector `json:"description"`
+}
+
+type Attributes struct {
+	Link          string `json:"link"`
+	Image         string `json:"image"`
+	NameFA        string `json:"name_fa"`
+	TrackTitle    string `json:"track_title"`
+	TrackArtist   string `json:"track_artist"`
+	TrackAlbum    string `json:"track_album"`
+	TrackInfo     string `json:"track_info"`
+	TrackImage    string `json:"track_image"`
+	TrackDuration string `json:"track_duration"`
+	TrackSrc      string `json:"track_src"`
+}
+
+// optionalSelectors may be left empty in a profile.
+var optionalSelectors = map[string]bool{
+	"listing.page_links": true,
+}
+
+// DetailFields are the card fields the listing detail rows can be mapped to.
+var DetailFields = []string{"name", "artist_name", "genre", "date"}
+
+// Load reads the profile at path, or the built-in SongSara profile when path is empty,
+// and validates it.
+func Load(path string) (*Profile, error) {
+	data := defaultProfile
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read selector profile: %w", err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var p Profile
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to decode selector profile %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid selector profile %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Validate checks the profile version and that every selector and attribute is set and
+// well-formed. It also names every selector so later mismatches can be reported by name.
+func (p *Profile) Validate() error {
+	if p.Version != Version {
+		return fmt.Errorf("unsupported version %d, expected %d", p.Version, Version)
+	}
+
+	var errs []error
+	selectors := p.selectors()
+	for _, name := range slices.Sorted(maps.Keys(selectors)) {
+		s := selectors[name]
+		s.Name = name
+		if s.CSS == "" {
+			if !optionalSelectors[name] {
+				errs = append(errs, fmt.Errorf("selector %s is missing", name))
+			}
+			continue
+		}
+		if _, err := cascadia.Parse(s.CSS); err != nil {
+			errs = append(errs, fmt.Errorf("selector %s (%q) is invalid: %w", name, s.CSS, err))
+		}
+	}
+	attributes := p.attributes()
+	for _, name := range slices.Sorted(maps.Keys(attributes)) {
+		if attributes[name] == "" {
+			errs = append(errs, fmt.Errorf("attribute %s is missing", name))
+		}
+	}
+	if p.Listing.TypeLabelIndex < 0 {
+		errs = append(errs, fmt.Errorf("listing.type_label_index must not be negative"))
+	}
+	for _, field := range p.Listing.DetailFields {
+		if !slices.Contains(DetailFields, field) {
+			errs = append(errs, fmt.Errorf("listing.detail_fields: unknown field %q", field))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Profile) selectors() map[string]*Selector {
+	return map[string]*Selector{
+		"moods.container":            &p.Moods.Container,
+		"moods.link":                 &p.Moods.Link,
+		"moods.name":                 &p.Moods.Name,
+		"listing.container":          &p.Listing.Container,
+		"listing.card":               &p.Listing.Card,
+		"listing.image":              &p.Listing.Image,
+		"listing.link":               &p.Listing.Link,
+		"listing.labels":             &p.Listing.Labels,
+		"listing.type":               &p.Listing.Type,
+		"listing.details":            &p.Listing.Details,
+		"listing.detail":             &p.Listing.Detail,
+		"listing.page_links":         &p.Listing.PageLinks,
+		"item.artists":               &p.Item.Artists,
+		"item.genres":                &p.Item.Genres,
+		"item.moods":                 &p.Item.Moods,
+		"item.instruments":           &p.Item.Instruments,
+		"item.publisher":             &p.Item.Publisher,
+		"item.taxon_link":            &p.Item.TaxonLink,
+		"item.player":                &p.Item.Player,
+		"item.track_list":            &p.Item.TrackList,
+		"item.track":                 &p.Item.Track,
+		"artist.image_box":           &p.Artist.ImageBox,
+		"artist.image":               &p.Artist.Image,
+		"artist.description_box":     &p.Artist.DescriptionBox,
+		"artist.description":         &p.Artist.Description,
+		"instrument.description_box": &p.Instrument.DescriptionBox,
+		"instrument.description":     &p.Instrument.Description,
+	}
+}
+
+func (p *Profile) attributes() map[string]string {
+	a := p.Attributes
+	return map[string]string{
+		"attributes.link":           a.Link,
+		"attributes.image":          a.Image,
+		"attributes.name_fa":        a.NameFA,
+		"attributes.track_title":    a.TrackTitle,
+		"attributes.track_artist":   a.TrackArtist,
+		"attributes.track_album":    a.TrackAlbum,
+		"attributes.track_info":     a.TrackInfo,
+		"attributes.track_image":    a.TrackImage,
+		"attributes.track_duration": a.TrackDuration,
+		"attributes.track_src":      a.TrackSrc,
+	}
+}
+
+// MismatchError reports a selector that no longer matches the page it is applied to,
+// which usually means the site changed its markup.
+type MismatchError struct {
+	Selector string
+	CSS      string
+	URL      string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("selector %s (%q) matched nothing on %s", e.Selector, e.CSS, e.URL)
+}
+
+// Selector is a CSS selector from the profile, named after its position in the profile.
+type Selector struct {
+	Name string
+	CSS  string
+}
+
+func (s *Selector) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &s.CSS)
+}
+
+// Find returns the first match of s below e, or a *MismatchError.
+func (s Selector) Find(e *page.Element) (*page.Element, error) {
+	found, err := e.FindElement(page.ByCSSSelector, s.CSS)
+	if errors.Is(err, page.ErrNoSuchElement) {
+		return nil, s.Mismatch(e)
+	}
+	return found, err
+}
+
+// FindAll returns every match of s below e, an empty result is not an error.
+func (s Selector) FindAll(e *page.Element) ([]*page.Element, error) {
+	return e.FindElements(page.ByCSSSelector, s.CSS)
+}
+
+func (s Selector) Mismatch(e *page.Element) error {
+	return &MismatchError{Selector: s.Name, CSS: s.CSS, URL: e.PageURL()}
+}
@@ -0,0 +1,49 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadDefault(t *testing.T) {
+	p, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if p.Listing.Card.Name != "listing.card" {
+		t.Errorf("Listing.Card.Name = %q, want listing.card", p.Listing.Card.Name)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Profile)
+		want   string // substring of the error, empty for a valid profile
+	}{
+		{"default", func(p *Profile) {}, ""},
+		{"no page links", func(p *Profile) { p.Listing.PageLinks.CSS = "" }, ""},
+		{"unsupported version", func(p *Profile) { p.Version = Version + 1 }, "unsupported version"},
+		{"missing selector", func(p *Profile) { p.Listing.Card.CSS = "" }, "selector listing.card is missing"},
+		{"invalid selector", func(p *Profile) { p.Item.Player.CSS = "div[" }, "selector item.player"},
+		{"missing attribute", func(p *Profile) { p.Attributes.TrackSrc = "" }, "attribute attributes.track_src is missing"},
+		{"negative label index", func(p *Profile) { p.Listing.TypeLabelIndex = -1 }, "type_label_index"},
+		{"unknown detail field", func(p *Profile) { p.Listing.DetailFields = []string{"name", "year"} }, `unknown field "year"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Load("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(p)
+			err = p.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Validate() error = %v, want nil", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Validate() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+	// DialTimeout bounds how long Dial waits for the broker to become reachable.
+	DialTimeout = 2 * time.Minute
+	// ConfirmTimeout bounds how long a publish waits for the broker to confirm it.
+	ConfirmTimeout = 10 * time.Second
+)
+
+var (
+	ErrConnClosed   = errors.New("connection closed")
+	ErrNotConnected = errors.New("not connected to RabbitMQ")
+	ErrNacked       = errors.New("message was nacked by the broker")
+	ErrUnroutable   = errors.New("message was returned as unroutable")
+	ErrNotConfirmed = errors.New("message was not confirmed in time")
+)
+
+// Conn is a RabbitMQ connection that heals itself. It watches the connection and its
+// channel and, when the broker goes away, reconnects with exponential backoff and opens a
+// fresh channel. Callers always ask Conn for the current channel instead of holding one.
+type Conn struct {
+	url string
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	ch      *amqp.Channel
+	changed chan struct{} // closed and replaced every time conn or ch changes
+	closed  bool
+	// declared holds the topics declared on ch, it is cleared with every change
+	declared map[string]bool
+
+	returnsMu sync.Mutex
+	returns   <-chan amqp.Return // unroutable messages of the current channel
+	waiting   map[string]int     // publishes awaiting their confirm by message ID
+	returned  map[string]int     // returns collected for waiting publishes
+
+	outbox *Outbox
+}
+
+// Dial connects to the broker, waiting up to DialTimeout for it to accept connections or
+// until ctx is done, and keeps the connection alive until Close.
+func Dial(ctx context.Context, rabbitURL string) (*Conn, error) {
+	c := &Conn{url: rabbitURL, changed: make(chan struct{}), waiting: make(map[string]int), returned: make(map[string]int)}
+
+	deadline := time.Now().Add(DialTimeout)
+	backoff := minBackoff
+	for {
+		err := c.connect()
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		slog.Warn("RabbitMQ is not ready yet, retrying", "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+
+	go c.watch()
+	return c, nil
+}
+
+func (c *Conn) connect() error {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	ch, err := c.openChannel(conn)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	c.conn, c.ch = conn, ch
+	c.notifyLocked()
+	c.mu.Unlock()
+	return nil
+}
+
+// openChannel opens a channel in confirm mode and subscribes to the messages the broker
+// returns as unroutable. Returns are read by the publishes waiting for them.
+func (c *Conn) openChannel(conn *amqp.Connection) (*amqp.Channel, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a channel: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 64))
+	c.returnsMu.Lock()
+	c.returns = returns
+	c.returnsMu.Unlock()
+	return ch, nil
+}
+
+// expectReturn registers a publish of messageID, returns of messages nobody waits for are
+// dropped.
+func (c *Conn) expectReturn(messageID string) {
+	c.returnsMu.Lock()
+	defer c.returnsMu.Unlock()
+	c.waiting[messageID]++
+}
+
+// forgetReturn unregisters a publish of messageID. It drains the returns as well, so the
+// returns of publishes that gave up waiting never fill the channel and block the client.
+func (c *Conn) forgetReturn(messageID string) {
+	c.returnsMu.Lock()
+	defer c.returnsMu.Unlock()
+	c.drainReturnsLocked()
+	if c.waiting[messageID]--; c.waiting[messageID] <= 0 {
+		delete(c.waiting, messageID)
+		delete(c.returned, messageID)
+	}
+}
+
+// wasReturned reports whether the broker returned messageID. The client delivers a return
+// before it resolves the confirm of the same message, so once the confirm arrived a return
+// is already waiting in the channel and draining it without blocking is enough.
+func (c *Conn) wasReturned(messageID string) bool {
+	c.returnsMu.Lock()
+	defer c.returnsMu.Unlock()
+	c.drainReturnsLocked()
+	if c.returned[messageID] == 0 {
+		return false
+	}
+	c.returned[messageID]--
+	return true
+}
+
+// drainReturnsLocked reads the returns already delivered. The client closes the returns
+// channel with the channel it belongs to, a closed one is dropped until openChannel
+// subscribes again.
+func (c *Conn) drainReturnsLocked() {
+	for {
+		select {
+		case r, ok := <-c.returns:
+			if !ok {
+				c.returns = nil
+				return
+			}
+			slog.Warn("message was returned", "message_id", r.MessageId, "queue", r.RoutingKey, "code", r.ReplyCode, "reason", r.ReplyText)
+			if c.waiting[r.MessageId] > 0 {
+				c.returned[r.MessageId]++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// publish sends msg to the queue through the default exchange as a mandatory message and
+// waits for the broker to confirm it. The publish fails when the broker nacks it, returns
+// it as unroutable or does not confirm it within ConfirmTimeout or before ctx is done.
+func (c *Conn) publish(ctx context.Context, ch *amqp.Channel, queueName string, msg amqp.Publishing) error {
+	ctx, cancel := context.WithTimeout(ctx, ConfirmTimeout)
+	defer cancel()
+
+	c.expectReturn(msg.MessageId)
+	defer c.forgetReturn(msg.MessageId)
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, "", queueName, true, false, msg)
+	if err != nil {
+		return err
+	}
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotConfirmed, err)
+	}
+	if !acked {
+		return ErrNacked
+	}
+	if c.wasReturned(msg.MessageId) {
+		return ErrUnroutable
+	}
+	return nil
+}
+
+func (c *Conn) notifyLocked() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+	c.declared = nil
+}
+
+// declareOnce runs declare unless the topic name was already declared on ch, the current
+// channel. A recovered channel declares every topic again.
+func (c *Conn) declareOnce(ch *amqp.Channel, name string, declare func(ch *amqp.Channel) error) error {
+	c.mu.Lock()
+	done := c.ch == ch && c.declared[name]
+	c.mu.Unlock()
+	if done {
+		return nil
+	}
+	if err := declare(ch); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ch == ch {
+		if c.declared == nil {
+			c.declared = make(map[string]bool)
+		}
+		c.declared[name] = true
+	}
+	return nil
+}
+
+// forgetDeclared makes the next publish to the topic name declare it again.
+func (c *Conn) forgetDeclared(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.declared, name)
+}
+
+// watch recovers the channel when only the channel died and the whole connection when the
+// connection died. It returns once Close is called.
+func (c *Conn) watch() {
+	for {
+		c.mu.Lock()
+		conn, ch := c.conn, c.ch
+		c.mu.Unlock()
+
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+		select {
+		case err := <-chClosed:
+			if c.isClosed() {
+				return
+			}
+			if !conn.IsClosed() {
+				slog.Warn("RabbitMQ channel closed, reopening it", "error", err)
+				if newCh, chErr := c.openChannel(conn); chErr == nil {
+					c.mu.Lock()
+					c.ch = newCh
+					c.notifyLocked()
+					c.mu.Unlock()
+					continue
+				}
+			}
+			c.reconnect(err)
+		case err := <-connClosed:
+			if c.isClosed() {
+				return
+			}
+			c.reconnect(err)
+		}
+		if c.isClosed() {
+			return
+		}
+	}
+}
+
+func (c *Conn) reconnect(cause *amqp.Error) {
+	slog.Warn("RabbitMQ connection lost, reconnecting", "error", cause)
+	backoff := minBackoff
+	for !c.isClosed() {
+		err := c.connect()
+		if err == nil {
+			slog.Info("Reconnected to RabbitMQ")
+			return
+		}
+		slog.Warn("Reconnecting to RabbitMQ failed, retrying", "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (c *Conn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// Channel returns the current open channel, waiting for a reconnect when there is none
+// until ctx is done.
+func (c *Conn) Channel(ctx context.Context) (*amqp.Channel, error) {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil, ErrConnClosed
+		}
+		ch, changed := c.ch, c.changed
+		c.mu.Unlock()
+
+		if ch != nil && !ch.IsClosed() {
+			return ch, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ErrNotConnected, ctx.Err())
+		}
+	}
+}
+
+// do runs fn on the current channel and runs it once more on the recovered channel when
+// the first one was closed underneath it. Waiting for a channel ends when ctx is done.
+func (c *Conn) do(ctx context.Context, fn func(ch *amqp.Channel) error) error {
+	var err error
+	for range 2 {
+		var ch *amqp.Channel
+		ch, err = c.Channel(ctx)
+		if err != nil {
+			return err
+		}
+		err = fn(ch)
+		if !errors.Is(err, amqp.ErrClosed) {
+			return err
+		}
+	}
+	return err
+}
+
+// Ping reports whether c is connected and has an open channel right now.
+func (c *Conn) Ping(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.closed:
+		return ErrConnClosed
+	case c.conn == nil || c.conn.IsClosed() || c.ch == nil || c.ch.IsClosed():
+		return ErrNotConnected
+	}
+	return nil
+}
+
+func (c *Conn) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed || c.conn == nil || c.conn.IsClosed()
+}
+
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	conn := c.conn
+	c.notifyLocked()
+	c.mu.Unlock()
+	return conn.Close()
+}
@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestReturns(t *testing.T) {
+	tests := []struct {
+		name     string
+		returned []string // message IDs the broker returned
+		close    bool     // the channel died after the returns
+		want     map[string]bool
+	}{
+		{"none", nil, false, map[string]bool{"a": false}},
+		{"returned", []string{"a"}, false, map[string]bool{"a": true, "b": false}},
+		{"not waited for", []string{"c"}, false, map[string]bool{"a": false}},
+		{"closed", nil, true, map[string]bool{"a": false}},
+		{"returned then closed", []string{"a"}, true, map[string]bool{"a": true, "b": false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			returns := make(chan amqp.Return, len(tt.returned))
+			c := &Conn{returns: returns, waiting: make(map[string]int), returned: make(map[string]int)}
+			for id := range tt.want {
+				c.expectReturn(id)
+			}
+			for _, id := range tt.returned {
+				returns <- amqp.Return{MessageId: id}
+			}
+			if tt.close {
+				close(returns)
+			}
+
+			done := make(chan map[string]bool)
+			go func() {
+				got := make(map[string]bool)
+				for id := range tt.want {
+					got[id] = c.wasReturned(id)
+					c.forgetReturn(id)
+				}
+				done <- got
+			}()
+			select {
+			case got := <-done:
+				for id, want := range tt.want {
+					if got[id] != want {
+						t.Errorf("wasReturned(%q) = %v, want %v", id, got[id], want)
+					}
+				}
+			case <-time.After(time.Second):
+				t.Fatal("draining the returns did not finish")
+			}
+			if len(c.waiting) != 0 || len(c.returned) != 0 {
+				t.Errorf("waiting = %v, returned = %v, want both empty", c.waiting, c.returned)
+			}
+		})
+	}
+}
+
+func TestDeclareOnce(t *testing.T) {
+	first, second := &amqp.Channel{}, &amqp.Channel{}
+	c := &Conn{ch: first, changed: make(chan struct{})}
+	declared := 0
+	declare := func(ch *amqp.Channel) error {
+		declared++
+		return nil
+	}
+	failing := func(ch *amqp.Channel) error { return errors.New("failed") }
+
+	steps := []struct {
+		name    string
+		before  func()
+		ch      *amqp.Channel
+		declare func(ch *amqp.Channel) error
+		err     bool
+		want    int // declarations so far
+	}{
+		{name: "first publish", ch: first, declare: declare, want: 1},
+		{name: "same channel", ch: first, declare: declare, want: 1},
+		{name: "stale channel", ch: second, declare: declare, want: 2},
+		{name: "stale channel again", ch: second, declare: declare, want: 3},
+		{name: "recovered channel", before: func() { c.ch = second; c.notifyLocked() }, ch: second, declare: declare, want: 4},
+		{name: "recovered channel again", ch: second, declare: declare, want: 4},
+		{name: "unroutable", before: func() { c.forgetDeclared("items") }, ch: second, declare: declare, want: 5},
+		{name: "failed declaration", before: func() { c.notifyLocked() }, ch: second, declare: failing, err: true, want: 5},
+		{name: "after a failed declaration", ch: second, declare: declare, want: 6},
+	}
+	for _, step := range steps {
+		if step.before != nil {
+			step.before()
+		}
+		if err := c.declareOnce(step.ch, "items", step.declare); (err != nil) != step.err {
+			t.Fatalf("%s: error %v", step.name, err)
+		}
+		if declared != step.want {
+			t.Errorf("%s: %d declarations, want %d", step.name, declared, step.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Outbox is an append-only JSON Lines file of messages that could not be published. They
+// are published again by Flush, usually on the next start.
+type Outbox struct {
+	path string
+	mu   sync.Mutex
+}
+
+type outboxEntry struct {
+	Queue       string         `json:"queue"`
+	MessageID   string         `json:"message_id"`
+	Type        string         `json:"type"`
+	ContentType string         `json:"content_type"`
+	Timestamp   time.Time      `json:"timestamp"`
+	Headers     map[string]any `json:"headers"`
+	Body        []byte         `json:"body"`
+	Error       string         `json:"error"`
+}
+
+func NewOutbox(path string) *Outbox {
+	return &Outbox{path: path}
+}
+
+// UseOutbox makes Topic.Publish park messages in outbox when publishing fails.
+func (c *Conn) UseOutbox(outbox *Outbox) {
+	c.outbox = outbox
+}
+
+func (o *Outbox) Add(queueName string, msg amqp.Publishing, cause error) error {
+	line, err := json.Marshal(outboxEntry{
+		Queue:       queueName,
+		MessageID:   msg.MessageId,
+		Type:        msg.Type,
+		ContentType: msg.ContentType,
+		Timestamp:   msg.Timestamp,
+		Headers:     msg.Headers,
+		Body:        msg.Body,
+		Error:       cause.Error(),
+	})
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	f, err := os.OpenFile(o.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Declarer is a queue that can be declared on a channel, every Topic is one.
+type Declarer interface {
+	Declare(ch *amqp.Channel) error
+}
+
+// Flush publishes every parked message again. The topics are declared first since messages
+// usually end up in the outbox because the broker lost its queues. Messages that still fail
+// or are not published before ctx is done stay in the outbox. It returns how many messages
+// were published.
+func (o *Outbox) Flush(ctx context.Context, c *Conn, topics ...Declarer) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries, err := o.read()
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+	for _, topic := range topics {
+		if err := c.do(ctx, topic.Declare); err != nil {
+			return 0, err
+		}
+	}
+
+	var remaining []outboxEntry
+	for _, entry := range entries {
+		msg := amqp.Publishing{
+			ContentType:  entry.ContentType,
+			Body:         entry.Body,
+			DeliveryMode: amqp.Persistent,
+			MessageId:    entry.MessageID,
+			Timestamp:    entry.Timestamp,
+			Type:         entry.Type,
+			Headers:      restoreHeaders(entry.Headers),
+		}
+		err := c.do(ctx, func(ch *amqp.Channel) error { return c.publish(ctx, ch, entry.Queue, msg) })
+		if err != nil {
+			entry.Error = err.Error()
+			remaining = append(remaining, entry)
+		}
+	}
+
+	if err := o.rewrite(remaining); err != nil {
+		return 0, err
+	}
+	return len(entries) - len(remaining), nil
+}
+
+func (o *Outbox) read() ([]outboxEntry, error) {
+	f, err := os.Open(o.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox: %w", err)
+	}
+	defer f.Close()
+
+	var entries []outboxEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry outboxEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("corrupted outbox entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func (o *Outbox) rewrite(entries []outboxEntry) error {
+	if len(entries) == 0 {
+		err := os.Remove(o.path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	tmp := o.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		_, _ = w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.path)
+}
+
+// restoreHeaders turns the whole numbers JSON decoded as float64 back into the int32
+// values the headers were published with.
+func restoreHeaders(headers map[string]any) amqp.Table {
+	table := amqp.Table{}
+	for k, v := range headers {
+		if f, ok := v.(float64); ok && f == math.Trunc(f) && f >= math.MinInt32 && f <= math.MaxInt32 {
+			v = int32(f)
+		}
+		table[k] = v
+	}
+	return table
+}
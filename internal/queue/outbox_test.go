@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestRestoreHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		in   map[string]any
+		want amqp.Table
+	}{
+		{"empty", nil, amqp.Table{}},
+		{"whole numbers", map[string]any{"x-attempt": float64(3), "x-page": float64(-1)}, amqp.Table{"x-attempt": int32(3), "x-page": int32(-1)}},
+		{"fraction", map[string]any{"ratio": 0.5}, amqp.Table{"ratio": 0.5}},
+		{"out of range", map[string]any{"big": float64(1 << 40)}, amqp.Table{"big": float64(1 << 40)}},
+		{"other types", map[string]any{"x-run-id": "r", "flag": true}, amqp.Table{"x-run-id": "r", "flag": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := restoreHeaders(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("restoreHeaders() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// disconnected is a Conn that never gets a channel.
+func disconnected() *Conn {
+	return &Conn{changed: make(chan struct{}), waiting: make(map[string]int), returned: make(map[string]int)}
+}
+
+func TestOutbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	o := NewOutbox(path)
+
+	msgs := []amqp.Publishing{
+		{MessageId: "a", Type: "items", ContentType: "application/json", Body: []byte(`{"a":1}`),
+			Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Headers: amqp.Table{headerSchemaVersion: int32(1), headerRunID: "r"}},
+		{MessageId: "b", Body: []byte("b")},
+	}
+	for _, msg := range msgs {
+		if err := o.Add("items", msg, errors.New("broker down")); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	entries, err := o.read()
+	if err != nil {
+		t.Fatalf("read() error = %v", err)
+	}
+	if len(entries) != len(msgs) {
+		t.Fatalf("outbox holds %d entries, want %d", len(entries), len(msgs))
+	}
+	first := entries[0]
+	if first.Queue != "items" || first.MessageID != "a" || string(first.Body) != `{"a":1}` || !first.Timestamp.Equal(msgs[0].Timestamp) || first.Error != "broker down" {
+		t.Errorf("entry = %+v, want message a", first)
+	}
+	if headers := restoreHeaders(first.Headers); !reflect.DeepEqual(headers, msgs[0].Headers) {
+		t.Errorf("headers = %#v, want %#v", headers, msgs[0].Headers)
+	}
+
+	// nothing is published or lost while the broker is away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if n, err := o.Flush(ctx, disconnected(), Items); !errors.Is(err, ErrNotConnected) || n != 0 {
+		t.Errorf("Flush() declaring topics = %d, %v, want 0, %v", n, err, ErrNotConnected)
+	}
+	if n, err := o.Flush(ctx, disconnected()); err != nil || n != 0 {
+		t.Errorf("Flush() = %d, %v, want 0, nil", n, err)
+	}
+	entries, err = o.read()
+	if err != nil || len(entries) != len(msgs) {
+		t.Fatalf("outbox after failed flushes holds %d entries, %v, want %d", len(entries), err, len(msgs))
+	}
+	if entries[0].MessageID != "a" || entries[0].Error == "broker down" {
+		t.Errorf("entry = %+v, want message a with the error of the flush", entries[0])
+	}
+
+	// rewriting without entries removes the file, an empty outbox flushes without a broker
+	if err := o.rewrite(nil); err != nil {
+		t.Fatalf("rewrite() error = %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("outbox file still exists: %v", err)
+	}
+	if n, err := o.Flush(ctx, nil, Items); err != nil || n != 0 {
+		t.Errorf("Flush() of an empty outbox = %d, %v, want 0, nil", n, err)
+	}
+}
+
+func TestOutboxCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	if err := os.WriteFile(path, []byte("{\"queue\":\"items\"}\n\nnot json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewOutbox(path).read(); err == nil {
+		t.Error("read() of a corrupted outbox succeeded")
+	}
+}
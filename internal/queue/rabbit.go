@@ -0,0 +1,257 @@
+// Package queue wraps RabbitMQ for the pipeline. Every queue is a Topic bound to the Go
+// type of its messages, so producer and consumer cannot disagree on the payload.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"song-sc/internal/model"
+)
+
+const (
+	headerSchemaVersion = "x-schema-version"
+	headerRunID         = "x-run-id"
+	headerSourceURL     = "x-source-url"
+	headerPage          = "x-page"
+)
+
+var ErrUnsupportedVersion = errors.New("unsupported schema version")
+
+// Items carries every item found by discovery to the detail stage.
+var Items = Topic[model.ItemMessage]{
+	Name:            "items",
+	ConsumerTimeout: 5 * time.Hour,
+	Retries:         RetryPolicy{MaxAttempts: 5, BaseDelay: 30 * time.Second, MaxDelay: 30 * time.Minute},
+}
+
+// Topic is a durable queue whose messages are JSON encoded values of T.
+type Topic[T any] struct {
+	Name            string
+	ConsumerTimeout time.Duration
+	Retries         RetryPolicy
+}
+
+func (t Topic[T]) declare(ch *amqp.Channel) (amqp.Queue, error) {
+	queue, err := ch.QueueDeclare(
+		t.Name,
+		true,
+		false,
+		false,
+		false,
+		amqp.Table{
+			amqp.ConsumerTimeoutArg: t.ConsumerTimeout.Milliseconds(),
+		}, // arguments
+	)
+	if err != nil {
+		return amqp.Queue{}, fmt.Errorf("failed to declare queue: %w", err)
+	}
+	if err := t.declareRetryTopology(ch); err != nil {
+		return amqp.Queue{}, err
+	}
+	return queue, nil
+}
+
+// Declare declares the queue of t and its retry topology on ch.
+func (t Topic[T]) Declare(ch *amqp.Channel) error {
+	_, err := t.declare(ch)
+	return err
+}
+
+func (t Topic[T]) consume(ch *amqp.Channel, tag string, prefetch int) (<-chan amqp.Delivery, error) {
+	queue, err := t.declare(ch)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ch.Qos(prefetch, 0, false) // Prefetch size: 0, Global: false
+	if err != nil {
+		return nil, fmt.Errorf("failed to set the qos: %w", err)
+	}
+	msgs, err := ch.Consume(
+		queue.Name,
+		tag,
+		false,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume: %w", err)
+	}
+	return msgs, nil
+}
+
+// Consume delivers the messages of t until ctx is done or c is closed, with at most
+// prefetch of them unacknowledged at a time. Whenever the connection or channel
+// is recovered, the queues are declared again and the consumer is re-established. Messages
+// that were unacknowledged when the channel died are redelivered by the broker.
+//
+// Once ctx is done the consumer is cancelled, the messages the broker had already pushed
+// but nobody took are requeued and the returned channel is closed. Messages handed out
+// before are left to the receiver to acknowledge.
+func (t Topic[T]) Consume(ctx context.Context, c *Conn, prefetch int) <-chan amqp.Delivery {
+	deliveries := make(chan amqp.Delivery)
+	tag := t.Name + "-" + model.NewID()
+	go func() {
+		defer close(deliveries)
+		for ctx.Err() == nil {
+			ch, err := c.Channel(ctx)
+			if err != nil {
+				return
+			}
+			msgs, err := t.consume(ch, tag, prefetch)
+			if err != nil {
+				slog.Warn("could not consume, retrying", "queue", t.Name, "error", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(minBackoff):
+				}
+				continue
+			}
+			if !t.forward(ctx, ch, tag, msgs, deliveries) {
+				return
+			}
+			slog.Warn("consumer stopped, waiting for the channel to recover", "queue", t.Name)
+		}
+	}()
+	return deliveries
+}
+
+// forward passes msgs on to deliveries. It returns true when the channel died and false
+// when ctx is done, after the consumer was cancelled.
+func (t Topic[T]) forward(ctx context.Context, ch *amqp.Channel, tag string, msgs <-chan amqp.Delivery, deliveries chan<- amqp.Delivery) bool {
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return true
+			}
+			select {
+			case deliveries <- msg:
+			case <-ctx.Done():
+				requeue(msg)
+				t.cancel(ch, tag, msgs)
+				return false
+			}
+		case <-ctx.Done():
+			t.cancel(ch, tag, msgs)
+			return false
+		}
+	}
+}
+
+// cancel stops the broker from pushing more messages and requeues those already pushed.
+func (t Topic[T]) cancel(ch *amqp.Channel, tag string, msgs <-chan amqp.Delivery) {
+	if err := ch.Cancel(tag, false); err != nil {
+		// a closed channel requeues its unacknowledged messages on its own
+		slog.Warn("could not cancel the consumer", "queue", t.Name, "error", err)
+		return
+	}
+	requeued := 0
+	for msg := range msgs {
+		requeue(msg)
+		requeued++
+	}
+	slog.Info("Stopped consuming", "queue", t.Name, "requeued", requeued)
+}
+
+func requeue(msg amqp.Delivery) {
+	if err := msg.Nack(false, true); err != nil {
+		slog.Warn("could not requeue message", "message_id", msg.MessageId, "error", err)
+	}
+}
+
+// Decode unmarshals the envelope of a delivery consumed from t. Every message on a topic
+// has been published in an envelope, so messages without one and versions newer than this
+// build understands are refused with ErrUnsupportedVersion instead of decoding to zero
+// values.
+func (t Topic[T]) Decode(msg amqp.Delivery) (model.Envelope[T], error) {
+	var envelope model.Envelope[T]
+
+	var probe struct {
+		SchemaVersion *int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(msg.Body, &probe); err != nil {
+		return envelope, fmt.Errorf("error unmarshalling message from %s: %w", t.Name, err)
+	}
+	if probe.SchemaVersion == nil {
+		return envelope, fmt.Errorf("%w: message on %s has no envelope", ErrUnsupportedVersion, t.Name)
+	}
+	version := *probe.SchemaVersion
+	if v, ok := msg.Headers[headerSchemaVersion].(int32); ok && int(v) != version {
+		return envelope, fmt.Errorf("schema version header %d does not match body version %d", v, version)
+	}
+	if version != model.SchemaVersion {
+		return envelope, fmt.Errorf("%w %d on %s, this build understands %d", ErrUnsupportedVersion, version, t.Name, model.SchemaVersion)
+	}
+	if err := json.Unmarshal(msg.Body, &envelope); err != nil {
+		return envelope, fmt.Errorf("error unmarshalling message from %s: %w", t.Name, err)
+	}
+	return envelope, nil
+}
+
+// Publish sends the envelope to t and waits for the broker to confirm it. The queue is
+// declared with the first publish on every channel. The message ID and production time are
+// filled in when missing and the envelope metadata is mirrored into the AMQP properties and
+// headers. When publishing fails, ctx being done included, and c has an outbox, the message
+// is parked there before the error is returned.
+func (t Topic[T]) Publish(ctx context.Context, c *Conn, envelope model.Envelope[T]) error {
+	envelope.SchemaVersion = model.SchemaVersion
+	if envelope.MessageID == "" {
+		envelope.MessageID = model.NewID()
+	}
+	if envelope.ProducedAt.IsZero() {
+		envelope.ProducedAt = time.Now().UTC()
+	}
+
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("error marshalling message: %w", err)
+	}
+
+	msg := amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         body,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    envelope.MessageID,
+		Timestamp:    envelope.ProducedAt,
+		Type:         t.Name,
+		Headers: amqp.Table{
+			headerSchemaVersion: int32(envelope.SchemaVersion),
+			headerRunID:         envelope.RunID,
+			headerSourceURL:     envelope.SourceURL,
+			headerPage:          int32(envelope.Page),
+		},
+	}
+	err = c.do(ctx, func(ch *amqp.Channel) error {
+		if err := c.declareOnce(ch, t.Name, t.Declare); err != nil {
+			return err
+		}
+		err := c.publish(ctx, ch, t.Name, msg)
+		if errors.Is(err, ErrUnroutable) {
+			// the queue is gone although it was declared on this channel
+			c.forgetDeclared(t.Name)
+		}
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to publish message %s to %s: %w", envelope.MessageID, t.Name, err)
+		if c.outbox != nil {
+			if parkErr := c.outbox.Add(t.Name, msg, err); parkErr != nil {
+				return errors.Join(err, fmt.Errorf("failed to park message in the outbox: %w", parkErr))
+			}
+			slog.Info("parked message in the outbox", "message_id", envelope.MessageID, "queue", t.Name)
+		}
+		return err
+	}
+
+	return nil
+}
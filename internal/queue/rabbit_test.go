@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		headers amqp.Table
+		wantErr error
+		wantURL string
+	}{
+		{
+			name:    "current version",
+			body:    `{"schema_version":1,"run_id":"r","payload":{"mood":"m","item":{"item_url":"u"}}}`,
+			wantURL: "u",
+		},
+		{name: "no envelope", body: `{"mood":"m","items":[{"item_url":"u"}]}`, wantErr: ErrUnsupportedVersion},
+		{name: "empty object", body: `{}`, wantErr: ErrUnsupportedVersion},
+		{name: "newer version", body: `{"schema_version":2}`, wantErr: ErrUnsupportedVersion},
+		{
+			name:    "header mismatch",
+			body:    `{"schema_version":1}`,
+			headers: amqp.Table{headerSchemaVersion: int32(2)},
+			wantErr: errors.New("any"),
+		},
+		{name: "not json", body: `not json`, wantErr: errors.New("any")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, err := Items.Decode(amqp.Delivery{Body: []byte(tt.body), Headers: tt.headers})
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("Decode() error = %v", err)
+			case tt.wantErr == ErrUnsupportedVersion && !errors.Is(err, ErrUnsupportedVersion):
+				t.Fatalf("Decode() error = %v, want %v", err, ErrUnsupportedVersion)
+			case tt.wantErr != nil && err == nil:
+				t.Fatalf("Decode() = %+v, want an error", envelope)
+			}
+			if got := envelope.Payload.Item.ItemURL; err == nil && got != tt.wantURL {
+				t.Errorf("item url = %q, want %q", got, tt.wantURL)
+			}
+		})
+	}
+}
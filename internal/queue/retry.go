@@ -0,0 +1,222 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	headerAttempt   = "x-attempt"
+	headerLastError = "x-last-error"
+	headerDeadAt    = "x-dead-at"
+)
+
+// RetryPolicy controls how often a failed message is retried and how long it waits in
+// between. The delay doubles with every attempt, starting at BaseDelay and capped at
+// MaxDelay. After MaxAttempts failed attempts the message goes to the dead-letter queue.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+func (t Topic[T]) retryQueue(attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", t.Name, attempt)
+}
+
+func (t Topic[T]) deadLetterQueue() string {
+	return t.Name + ".dlq"
+}
+
+// declareRetryTopology declares one delay queue per retry attempt and the dead-letter
+// queue. A delay queue has no consumer, its messages expire after the attempt's delay and
+// are dead-lettered back to the topic queue.
+func (t Topic[T]) declareRetryTopology(ch *amqp.Channel) error {
+	for attempt := 1; attempt < t.Retries.MaxAttempts; attempt++ {
+		_, err := ch.QueueDeclare(
+			t.retryQueue(attempt),
+			true,
+			false,
+			false,
+			false,
+			amqp.Table{
+				amqp.QueueMessageTTLArg:     t.Retries.delay(attempt).Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": t.Name,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue: %w", err)
+		}
+	}
+	_, err := ch.QueueDeclare(t.deadLetterQueue(), true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue: %w", err)
+	}
+	return nil
+}
+
+// Attempts returns how many times the delivery has already failed.
+func Attempts(msg amqp.Delivery) int {
+	attempts, _ := msg.Headers[headerAttempt].(int32)
+	return int(attempts)
+}
+
+// Retry schedules a failed delivery for another attempt after the backoff delay, or moves
+// it to the dead-letter queue once the retry policy is exhausted. The delivery is acked
+// once it has been republished. Waiting for the broker ends when ctx is done.
+func (t Topic[T]) Retry(ctx context.Context, c *Conn, msg amqp.Delivery, cause error) error {
+	attempt := Attempts(msg) + 1
+	if attempt >= t.Retries.MaxAttempts {
+		return t.DeadLetter(ctx, c, msg, cause)
+	}
+	headers := withHeaders(msg.Headers, amqp.Table{
+		headerAttempt:   int32(attempt),
+		headerLastError: cause.Error(),
+	})
+	if err := c.do(ctx, func(ch *amqp.Channel) error { return republish(ctx, c, ch, t.retryQueue(attempt), msg, headers) }); err != nil {
+		return fmt.Errorf("failed to schedule retry %d of %s: %w", attempt, msg.MessageId, err)
+	}
+	return msg.Ack(false)
+}
+
+// DeadLetter moves a delivery straight to the dead-letter queue, recording why. It is used
+// for messages that can never succeed, such as undecodable bodies.
+func (t Topic[T]) DeadLetter(ctx context.Context, c *Conn, msg amqp.Delivery, cause error) error {
+	headers := withHeaders(msg.Headers, amqp.Table{
+		headerAttempt:   int32(Attempts(msg) + 1),
+		headerLastError: cause.Error(),
+		headerDeadAt:    time.Now().UTC().Format(time.RFC3339),
+	})
+	if err := c.do(ctx, func(ch *amqp.Channel) error { return republish(ctx, c, ch, t.deadLetterQueue(), msg, headers) }); err != nil {
+		return fmt.Errorf("failed to dead-letter %s: %w", msg.MessageId, err)
+	}
+	return msg.Ack(false)
+}
+
+// DeadLetterInfo describes a message sitting in the dead-letter queue.
+type DeadLetterInfo struct {
+	MessageID string
+	Attempts  int
+	LastError string
+	DeadAt    string
+	Body      []byte
+}
+
+// DeadLetters lists the dead-letter queue of t without removing anything from it.
+func (t Topic[T]) DeadLetters(ctx context.Context, c *Conn) ([]DeadLetterInfo, error) {
+	ch, err := c.Channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.declare(ch); err != nil {
+		return nil, err
+	}
+	var infos []DeadLetterInfo
+	var lastTag uint64
+	for {
+		msg, ok, err := ch.Get(t.deadLetterQueue(), false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dead-letter queue: %w", err)
+		}
+		if !ok {
+			break
+		}
+		lastTag = msg.DeliveryTag
+		lastError, _ := msg.Headers[headerLastError].(string)
+		deadAt, _ := msg.Headers[headerDeadAt].(string)
+		infos = append(infos, DeadLetterInfo{
+			MessageID: msg.MessageId,
+			Attempts:  Attempts(msg),
+			LastError: lastError,
+			DeadAt:    deadAt,
+			Body:      msg.Body,
+		})
+	}
+	if lastTag != 0 {
+		if err := ch.Nack(lastTag, true, true); err != nil {
+			return nil, fmt.Errorf("failed to return messages to the dead-letter queue: %w", err)
+		}
+	}
+	return infos, nil
+}
+
+// ReplayDeadLetters moves dead letters back to the topic queue with a fresh attempt count.
+// An empty messageID replays the whole dead-letter queue. It returns the number of
+// replayed messages.
+func (t Topic[T]) ReplayDeadLetters(ctx context.Context, c *Conn, messageID string) (int, error) {
+	ch, err := c.Channel(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := t.declare(ch); err != nil {
+		return 0, err
+	}
+	var kept []uint64
+	defer func() {
+		for _, tag := range kept {
+			_ = ch.Nack(tag, false, true)
+		}
+	}()
+
+	replayed := 0
+	for {
+		msg, ok, err := ch.Get(t.deadLetterQueue(), false)
+		if err != nil {
+			return replayed, fmt.Errorf("failed to read dead-letter queue: %w", err)
+		}
+		if !ok {
+			return replayed, nil
+		}
+		if messageID != "" && msg.MessageId != messageID {
+			kept = append(kept, msg.DeliveryTag)
+			continue
+		}
+		headers := withHeaders(msg.Headers, nil)
+		delete(headers, headerAttempt)
+		delete(headers, headerLastError)
+		delete(headers, headerDeadAt)
+		if err := republish(ctx, c, ch, t.Name, msg, headers); err != nil {
+			kept = append(kept, msg.DeliveryTag)
+			return replayed, fmt.Errorf("failed to replay %s: %w", msg.MessageId, err)
+		}
+		if err := msg.Ack(false); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+}
+
+func withHeaders(headers amqp.Table, overrides amqp.Table) amqp.Table {
+	merged := amqp.Table{}
+	for k, v := range headers {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+func republish(ctx context.Context, c *Conn, ch *amqp.Channel, queueName string, msg amqp.Delivery, headers amqp.Table) error {
+	return c.publish(ctx, ch, queueName, amqp.Publishing{
+		ContentType:  msg.ContentType,
+		Body:         msg.Body,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.MessageId,
+		Timestamp:    msg.Timestamp,
+		Type:         msg.Type,
+		Headers:      headers,
+	})
+}
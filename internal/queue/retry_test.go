@@ -0,0 +1,26 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 6, BaseDelay: 30 * time.Second, MaxDelay: 3 * time.Minute}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 3 * time.Minute},
+		{5, 3 * time.Minute},
+		{100, 3 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := policy.delay(tt.attempt); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
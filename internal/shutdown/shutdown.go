@@ -0,0 +1,54 @@
+// Package shutdown turns SIGINT and SIGTERM into context cancellation, so the pipeline
+// stages can stop taking work, finish what they hold and exit cleanly.
+package shutdown
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Notify returns a context that is cancelled on the first SIGINT or SIGTERM. A second
+// signal exits the process right away. Code reports the exit status for the signal that
+// was received, 128 plus the signal number as shells do, or 0 when none was.
+func Notify(parent context.Context) (ctx context.Context, code func() int) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	var (
+		mu       sync.Mutex
+		received os.Signal
+	)
+	go func() {
+		sig := <-signals
+		mu.Lock()
+		received = sig
+		mu.Unlock()
+		slog.Info("Shutting down, send the signal again to exit immediately", "signal", sig.String())
+		cancel()
+
+		sig = <-signals
+		slog.Warn("Received a second signal, exiting", "signal", sig.String())
+		os.Exit(exitCode(sig))
+	}()
+
+	return ctx, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		if received == nil {
+			return 0
+		}
+		return exitCode(received)
+	}
+}
+
+func exitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
@@ -0,0 +1,377 @@
+package store
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"song-sc/internal/model"
+)
+
+// Catalog is the content of a sink read back into memory, every record keyed by its ID.
+type Catalog struct {
+	Albums      map[string]model.Album
+	Tracks      map[string]model.Track
+	Artists     map[string]model.Artist
+	Instruments map[string]model.Instrument
+	Genres      map[string]model.Genre
+	Moods       map[string]model.MoodData
+	Publishers  map[string]model.Publisher
+}
+
+func newCatalog() *Catalog {
+	return &Catalog{
+		Albums:      make(map[string]model.Album),
+		Tracks:      make(map[string]model.Track),
+		Artists:     make(map[string]model.Artist),
+		Instruments: make(map[string]model.Instrument),
+		Genres:      make(map[string]model.Genre),
+		Moods:       make(map[string]model.MoodData),
+		Publishers:  make(map[string]model.Publisher),
+	}
+}
+
+func (c *Catalog) addItem(item Item) {
+	if item.Album {
+		album := item.AlbumRecord()
+		c.Albums[album.ID] = album
+	}
+	for _, track := range item.TrackRecords() {
+		c.Tracks[track.ID] = track
+	}
+}
+
+// DetectConfig guesses the sink that wrote path: a directory holding items.jsonl is a JSON
+// Lines export, any other directory the directory layout and a file an SQLite database.
+func DetectConfig(path string) (Config, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if !info.IsDir() {
+		return Config{Kind: KindSQLite, Path: path}, nil
+	}
+	if _, err := os.Stat(filepath.Join(path, "items.jsonl")); err == nil {
+		return Config{Kind: KindJSONL, Path: path}, nil
+	}
+	return Config{Kind: KindDir, Path: path}, nil
+}
+
+// LoadCatalog reads everything the configured sink has written. The directory layout does
+// not keep the item an album track belongs to, so only catalogs written by the same kind of
+// sink compare cleanly.
+func LoadCatalog(cfg Config) (*Catalog, error) {
+	switch cfg.Kind {
+	case "", KindDir:
+		if cfg.Path == "" {
+			cfg.Path = "."
+		}
+		return loadDir(cfg.Path)
+	case KindJSONL:
+		return loadJSONL(cfg.Path)
+	case KindSQLite:
+		return loadSQLite(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unknown sink %q", cfg.Kind)
+	}
+}
+
+func loadDir(root string) (*Catalog, error) {
+	c := newCatalog()
+	entities := []struct {
+		kind string
+		add  func([]byte) error
+	}{
+		{"artists", decodeInto(c.Artists, func(v model.Artist) string { return v.ID })},
+		{"instruments", decodeInto(c.Instruments, func(v model.Instrument) string { return v.ID })},
+		{"genres", decodeInto(c.Genres, func(v model.Genre) string { return v.ID })},
+		{"mooddata", decodeInto(c.Moods, func(v model.MoodData) string { return v.ID })},
+		{"publishers", decodeInto(c.Publishers, func(v model.Publisher) string { return v.ID })},
+	}
+	for _, entity := range entities {
+		if err := walkJSON(filepath.Join(root, "data", entity.kind), entity.add); err != nil {
+			return nil, err
+		}
+	}
+
+	err := walkJSON(filepath.Join(root, "songs"), func(data []byte) error {
+		var probe struct {
+			Tracks json.RawMessage `json:"tracks"`
+		}
+		if err := json.Unmarshal(data, &probe); err != nil {
+			return err
+		}
+		if probe.Tracks == nil {
+			var track model.Track
+			if err := json.Unmarshal(data, &track); err != nil {
+				return err
+			}
+			c.Tracks[track.ID] = track
+			return nil
+		}
+		var album model.Album
+		if err := json.Unmarshal(data, &album); err != nil {
+			return err
+		}
+		c.Albums[album.ID] = album
+		for _, t := range album.Tracks {
+			c.Tracks[t.ID] = model.Track{
+				ID:       t.ID,
+				Title:    t.Title,
+				Album:    album.Name,
+				Info:     t.Info,
+				Duration: t.Duration,
+				MP3Link:  t.MP3Link,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// walkJSON calls fn with the content of every .json file below dir. Hidden directories
+// hold items that are still being written and are skipped.
+func walkJSON(dir string, fn func([]byte) error) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func decodeInto[T any](records map[string]T, id func(T) string) func([]byte) error {
+	return func(data []byte) error {
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		records[id(v)] = v
+		return nil
+	}
+}
+
+func loadJSONL(dir string) (*Catalog, error) {
+	c := newCatalog()
+	// an item saved again replaces its earlier line, tracks it lost included
+	items := make(map[string]Item)
+	var order []string
+	files := []struct {
+		kind string
+		add  func([]byte) error
+	}{
+		{"artists", decodeInto(c.Artists, func(v model.Artist) string { return v.ID })},
+		{"instruments", decodeInto(c.Instruments, func(v model.Instrument) string { return v.ID })},
+		{"genres", decodeInto(c.Genres, func(v model.Genre) string { return v.ID })},
+		{"moods", decodeInto(c.Moods, func(v model.MoodData) string { return v.ID })},
+		{"publishers", decodeInto(c.Publishers, func(v model.Publisher) string { return v.ID })},
+		{"items", func(data []byte) error {
+			var item Item
+			if err := json.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			if _, ok := items[item.ID]; !ok {
+				order = append(order, item.ID)
+			}
+			items[item.ID] = item
+			return nil
+		}},
+	}
+	for _, file := range files {
+		if err := readLines(filepath.Join(dir, file.kind+".jsonl"), file.add); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range order {
+		c.addItem(items[id])
+	}
+	return c, nil
+}
+
+// readLines calls fn with every line of the JSON Lines file at path, later lines overwrite
+// earlier records with the same ID.
+func readLines(path string, fn func([]byte) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return fmt.Errorf("failed to read %s line %d: %w", path, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func loadSQLite(path string) (*Catalog, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	s, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.catalog()
+}
+
+func (s *SQLite) catalog() (*Catalog, error) {
+	c := newCatalog()
+	queries := []struct {
+		query string
+		scan  func(*sql.Rows) error
+	}{
+		{`SELECT id, name_en, name_fa, description, img FROM artists`, func(rows *sql.Rows) error {
+			var v model.Artist
+			err := rows.Scan(&v.ID, &v.NameEN, &v.NameFA, &v.Description, &v.Img)
+			c.Artists[v.ID] = v
+			return err
+		}},
+		{`SELECT id, name_en, name_fa, description FROM instruments`, func(rows *sql.Rows) error {
+			var v model.Instrument
+			err := rows.Scan(&v.ID, &v.NameEN, &v.NameFA, &v.Description)
+			c.Instruments[v.ID] = v
+			return err
+		}},
+		{`SELECT id, name_en, name_fa FROM genres`, func(rows *sql.Rows) error {
+			var v model.Genre
+			err := rows.Scan(&v.ID, &v.NameEN, &v.NameFA)
+			c.Genres[v.ID] = v
+			return err
+		}},
+		{`SELECT id, name_en, name_fa FROM moods`, func(rows *sql.Rows) error {
+			var v model.MoodData
+			err := rows.Scan(&v.ID, &v.NameEN, &v.NameFA)
+			c.Moods[v.ID] = v
+			return err
+		}},
+		{`SELECT id, name_en FROM publishers`, func(rows *sql.Rows) error {
+			var v model.Publisher
+			err := rows.Scan(&v.ID, &v.NameEN)
+			c.Publishers[v.ID] = v
+			return err
+		}},
+	}
+	for _, q := range queries {
+		if err := s.each(q.query, q.scan); err != nil {
+			return nil, err
+		}
+	}
+
+	items := make(map[string]*Item)
+	var order []string
+	err := s.each(`SELECT i.id, i.url, i.mood, i.name, i.type, i.image, a.id IS NOT NULL,
+			COALESCE(p.id, ''), COALESCE(p.name_en, '')
+		FROM items i LEFT JOIN albums a ON a.item_id = i.id LEFT JOIN publishers p ON p.id = i.publisher_id`,
+		func(rows *sql.Rows) error {
+			var item Item
+			err := rows.Scan(&item.ID, &item.URL, &item.Mood, &item.Name, &item.Type, &item.Image, &item.Album, &item.PublisherID, &item.Publisher)
+			items[item.ID] = &item
+			order = append(order, item.ID)
+			return err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	links := []struct {
+		query      string
+		ids, names func(*Item) *[]string
+	}{
+		{`SELECT l.item_id, e.id, e.name_en FROM item_artists l JOIN artists e ON e.id = l.artist_id ORDER BY l.rowid`,
+			func(i *Item) *[]string { return &i.ArtistIDs }, func(i *Item) *[]string { return &i.Artists }},
+		{`SELECT l.item_id, e.id, e.name_en FROM item_genres l JOIN genres e ON e.id = l.genre_id ORDER BY l.rowid`,
+			func(i *Item) *[]string { return &i.GenreIDs }, func(i *Item) *[]string { return &i.Genres }},
+		{`SELECT l.item_id, e.id, e.name_en FROM item_moods l JOIN moods e ON e.id = l.mood_id ORDER BY l.rowid`,
+			func(i *Item) *[]string { return &i.MoodIDs }, func(i *Item) *[]string { return &i.Moods }},
+		{`SELECT l.item_id, e.id, e.name_en FROM item_instruments l JOIN instruments e ON e.id = l.instrument_id ORDER BY l.rowid`,
+			func(i *Item) *[]string { return &i.InstrumentIDs }, func(i *Item) *[]string { return &i.Instruments }},
+	}
+	for _, link := range links {
+		err := s.each(link.query, func(rows *sql.Rows) error {
+			var itemID, id, name string
+			if err := rows.Scan(&itemID, &id, &name); err != nil {
+				return err
+			}
+			if item, ok := items[itemID]; ok {
+				*link.ids(item) = append(*link.ids(item), id)
+				*link.names(item) = append(*link.names(item), name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.each(`SELECT item_id, id, title, artist, album, info, image, duration, mp3_link FROM tracks ORDER BY item_id, position`,
+		func(rows *sql.Rows) error {
+			var itemID string
+			var t model.Track
+			if err := rows.Scan(&itemID, &t.ID, &t.Title, &t.Artist, &t.Album, &t.Info, &t.Image, &t.Duration, &t.MP3Link); err != nil {
+				return err
+			}
+			if item, ok := items[itemID]; ok {
+				item.Tracks = append(item.Tracks, t)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range order {
+		c.addItem(*items[id])
+	}
+	return c, nil
+}
+
+func (s *SQLite) each(query string, scan func(*sql.Rows) error) error {
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to read catalog: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return fmt.Errorf("failed to read catalog: %w", err)
+		}
+	}
+	return rows.Err()
+}
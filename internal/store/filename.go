@@ -0,0 +1,90 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"song-sc/internal/metrics"
+)
+
+// maxNameBytes keeps names well below the 255 byte limit of common filesystems once the
+// ID suffix and extension are added, Persian titles take two bytes per letter.
+const maxNameBytes = 120
+
+// safeName turns name into a single path element: separators, control characters and
+// characters Windows refuses are replaced, leading dots and surrounding spaces removed and
+// the result cut to maxNameBytes on a rune boundary.
+func safeName(name string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\':
+			return '-'
+		case unicode.IsControl(r) || r == utf8.RuneError:
+			return -1
+		case strings.ContainsRune(`:*?"<>|`, r):
+			return '-'
+		}
+		return r
+	}, name)
+	cleaned = strings.TrimLeft(strings.TrimSpace(cleaned), ".")
+	cleaned = strings.TrimSpace(cleaned)
+	if len(cleaned) > maxNameBytes {
+		cut := maxNameBytes
+		for cut > 0 && !utf8.RuneStart(cleaned[cut]) {
+			cut--
+		}
+		cleaned = strings.TrimSpace(cleaned[:cut])
+	}
+	if cleaned == "" {
+		cleaned = "untitled"
+	}
+	return cleaned
+}
+
+// uniqueName is safeName followed by a short hash of key, so records whose names clean up
+// to the same string, or are empty, still get their own file. The same key always yields
+// the same name.
+func uniqueName(name, key string) string {
+	return safeName(name) + "-" + nameSuffix(key)
+}
+
+// nameSuffix is the hash of key that uniqueName appends.
+func nameSuffix(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place,
+// so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	metrics.BytesWritten.WithLabelValues(KindDir).Add(float64(len(data)))
+	return nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSafeName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Item 1", "Item 1"},
+		{"AC/DC", "AC-DC"},
+		{`a\b`, "a-b"},
+		{`what?: "yes" <no> | *`, "what-- -yes- -no- - -"},
+		{"../../etc/passwd", "-..-etc-passwd"},
+		{"  .hidden ", "hidden"},
+		{"tab\there\n", "tabhere"},
+		{"آهنگ شاد", "آهنگ شاد"},
+		{"", "untitled"},
+		{"...", "untitled"},
+	}
+	for _, tt := range tests {
+		if got := safeName(tt.in); got != tt.want {
+			t.Errorf("safeName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSafeNameLength(t *testing.T) {
+	long := strings.Repeat("آ", maxNameBytes) // two bytes per letter
+	got := safeName(long)
+	if len(got) > maxNameBytes {
+		t.Errorf("len(safeName) = %d, want at most %d", len(got), maxNameBytes)
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("safeName cut a rune in half: %q", got)
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	a := uniqueName("Track", "track-1")
+	if a != uniqueName("Track", "track-1") {
+		t.Error("uniqueName is not stable")
+	}
+	if a == uniqueName("Track", "track-2") {
+		t.Error("uniqueName of different keys collide")
+	}
+	if uniqueName("a/b", "k") == uniqueName("a-b", "other") {
+		t.Error("names cleaning up to the same string collide")
+	}
+	if !strings.HasPrefix(a, "Track-") || !strings.HasSuffix(a, nameSuffix("track-1")) {
+		t.Errorf("uniqueName = %q, want Track-%s", a, nameSuffix("track-1"))
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.json")
+	for _, data := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(data)); err != nil {
+			t.Fatalf("writeFileAtomic() error = %v", err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil || string(got) != data {
+			t.Errorf("file = %q, %v, want %q", got, err, data)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only a.json", len(entries))
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "a.json"), nil); err == nil {
+		t.Error("writeFileAtomic() into a missing directory succeeded")
+	}
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"song-sc/internal/health"
+	"song-sc/internal/model"
+)
+
+var _ Sink = (*Dir)(nil)
+
+// Dir writes one JSON file per record: entities go to data/<kind>/<name>.json and items to
+// songs/<mood>/<type>/<item>/, an album as a single file and other items as a file per
+// track. File and item directory names are made safe and get an ID suffix, the original
+// name is kept in the JSON.
+type Dir struct {
+	root string
+}
+
+// NewDir creates the directory layout below root.
+func NewDir(root string) (*Dir, error) {
+	d := &Dir{root: root}
+	for _, kind := range []string{"publishers", "artists", "genres", "instruments", "mooddata"} {
+		path := filepath.Join(root, "data", kind)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory '%s': %w", path, err)
+		}
+	}
+	return d, nil
+}
+
+func (d *Dir) SaveArtist(artist model.Artist) error {
+	return d.writeEntity("artists", artist.NameEN, artist.ID, artist)
+}
+
+func (d *Dir) SaveInstrument(instrument model.Instrument) error {
+	return d.writeEntity("instruments", instrument.NameEN, instrument.ID, instrument)
+}
+
+func (d *Dir) SaveGenre(genre model.Genre) error {
+	return d.writeEntity("genres", genre.NameEN, genre.ID, genre)
+}
+
+func (d *Dir) SaveMood(mood model.MoodData) error {
+	return d.writeEntity("mooddata", mood.NameEN, mood.ID, mood)
+}
+
+func (d *Dir) SavePublisher(publisher model.Publisher) error {
+	return d.writeEntity("publishers", publisher.NameEN, publisher.ID, publisher)
+}
+
+// writeEntity stores a taxonomy entity as data/<kind>/<name>-<id>.json.
+func (d *Dir) writeEntity(kind, name, id string, entity any) error {
+	bytes, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+	fileName := filepath.Join(d.root, "data", kind, uniqueName(name, id)+".json")
+	return writeFileAtomic(fileName, bytes)
+}
+
+// SaveItem writes the item into a fresh directory and swaps it with the directory of the
+// previous save, so files of tracks that are gone do not linger. Directories the item had
+// under another name or type are removed. Readers may briefly see no directory for the
+// item, never a partial one.
+func (d *Dir) SaveItem(ctx context.Context, item Item) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to save item %s: %w", item.URL, err)
+	}
+	moodPath := filepath.Join(d.root, "songs", safeName(item.Mood))
+	typePath := filepath.Join(moodPath, safeName(item.Type))
+	itemPath := filepath.Join(typePath, uniqueName(item.Name, item.ID))
+	if err := os.MkdirAll(typePath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory `%s`: %w", typePath, err)
+	}
+	tmp, err := os.MkdirTemp(typePath, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to save item %s: %w", item.URL, err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return fmt.Errorf("failed to save item %s: %w", item.URL, err)
+	}
+	if err := d.writeItem(tmp, item); err != nil {
+		return fmt.Errorf("failed to save item %s: %w", item.URL, err)
+	}
+
+	if _, err := os.Stat(itemPath); err == nil {
+		old := tmp + ".old"
+		if err := os.Rename(itemPath, old); err != nil {
+			return fmt.Errorf("failed to save item %s: %w", item.URL, err)
+		}
+		defer os.RemoveAll(old)
+	}
+	if err := os.Rename(tmp, itemPath); err != nil {
+		return fmt.Errorf("failed to save item %s: %w", item.URL, err)
+	}
+
+	stale, err := itemDirs(moodPath, item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to find old directories of item %s: %w", item.URL, err)
+	}
+	for _, old := range stale {
+		if old == itemPath {
+			continue
+		}
+		if err := os.RemoveAll(old); err != nil {
+			return fmt.Errorf("failed to remove the old directory of item %s: %w", item.URL, err)
+		}
+	}
+	return nil
+}
+
+// itemDirs lists the directories of the item with id below every type of moodPath.
+func itemDirs(moodPath, id string) ([]string, error) {
+	suffix := "-" + nameSuffix(id)
+	types, err := os.ReadDir(moodPath)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, t := range types {
+		if !t.IsDir() || strings.HasPrefix(t.Name(), ".") {
+			continue
+		}
+		items, err := os.ReadDir(filepath.Join(moodPath, t.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range items {
+			if entry.IsDir() && strings.HasSuffix(entry.Name(), suffix) {
+				dirs = append(dirs, filepath.Join(moodPath, t.Name(), entry.Name()))
+			}
+		}
+	}
+	return dirs, nil
+}
+
+// writeItem writes the files of item into dir, an album as a single file and other items as
+// a file per track.
+func (d *Dir) writeItem(dir string, item Item) error {
+	if item.Album {
+		bytes, err := json.Marshal(item.AlbumRecord())
+		if err != nil {
+			return fmt.Errorf("failed to marshal album: %w", err)
+		}
+		return writeFileAtomic(filepath.Join(dir, uniqueName(item.Name, item.ID)+".json"), bytes)
+	}
+
+	used := make(map[string]bool)
+	for i, track := range item.TrackRecords() {
+		trackBytes, err := json.Marshal(track)
+		if err != nil {
+			return fmt.Errorf("failed to marshal track: %w", err)
+		}
+		// the track ID follows the mp3, so a reordered player keeps its file names. Tracks
+		// without an mp3, or sharing one, fall back to their position
+		key := track.ID
+		if track.MP3Link == "" || used[key] {
+			key = fmt.Sprintf("%s#%d", item.ID, i)
+		}
+		used[key] = true
+		if err := writeFileAtomic(filepath.Join(dir, uniqueName(track.Title, key)+".json"), trackBytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dir) Ping(ctx context.Context) error {
+	return health.Writable(d.root)(ctx)
+}
+
+func (d *Dir) Close() error {
+	return nil
+}
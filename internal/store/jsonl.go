@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"song-sc/internal/health"
+	"song-sc/internal/metrics"
+	"song-sc/internal/model"
+)
+
+var _ Sink = (*JSONL)(nil)
+
+// JSONL appends every record to a JSON Lines file per record type in one directory, for
+// bulk loading into other systems. Records saved twice appear twice, the last one wins.
+type JSONL struct {
+	dir   string
+	mu    sync.Mutex
+	files map[string]*os.File
+}
+
+var jsonlKinds = []string{"artists", "instruments", "genres", "moods", "publishers", "items"}
+
+// NewJSONL opens <dir>/<type>.jsonl for every record type, appending to existing files.
+func NewJSONL(dir string) (*JSONL, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+	j := &JSONL{dir: dir, files: make(map[string]*os.File)}
+	for _, kind := range jsonlKinds {
+		f, err := os.OpenFile(filepath.Join(dir, kind+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			_ = j.Close()
+			return nil, fmt.Errorf("failed to open %s export: %w", kind, err)
+		}
+		j.files[kind] = f
+	}
+	return j, nil
+}
+
+func (j *JSONL) SaveArtist(artist model.Artist) error {
+	return j.append("artists", artist)
+}
+
+func (j *JSONL) SaveInstrument(instrument model.Instrument) error {
+	return j.append("instruments", instrument)
+}
+
+func (j *JSONL) SaveGenre(genre model.Genre) error {
+	return j.append("genres", genre)
+}
+
+func (j *JSONL) SaveMood(mood model.MoodData) error {
+	return j.append("moods", mood)
+}
+
+func (j *JSONL) SavePublisher(publisher model.Publisher) error {
+	return j.append("publishers", publisher)
+}
+
+// SaveItem writes the item with its tracks as a single line, so it is atomic as long as
+// the line is.
+func (j *JSONL) SaveItem(ctx context.Context, item Item) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to save item %s: %w", item.URL, err)
+	}
+	return j.append("items", item)
+}
+
+func (j *JSONL) append(kind string, record any) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s record: %w", kind, err)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.files[kind].Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s record: %w", kind, err)
+	}
+	metrics.BytesWritten.WithLabelValues(KindJSONL).Add(float64(len(line) + 1))
+	return nil
+}
+
+func (j *JSONL) Ping(ctx context.Context) error {
+	return health.Writable(j.dir)(ctx)
+}
+
+func (j *JSONL) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var errs []error
+	for _, f := range j.files {
+		errs = append(errs, f.Sync(), f.Close())
+	}
+	return errors.Join(errs...)
+}
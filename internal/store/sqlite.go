@@ -0,0 +1,288 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"song-sc/internal/model"
+)
+
+// Rows are keyed by the IDs of the records, see model.EntityID.
+const schema = `
+CREATE TABLE IF NOT EXISTS artists (
+	id          TEXT PRIMARY KEY,
+	name_en     TEXT NOT NULL,
+	name_fa     TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	img         TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS instruments (
+	id          TEXT PRIMARY KEY,
+	name_en     TEXT NOT NULL,
+	name_fa     TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS genres (
+	id      TEXT PRIMARY KEY,
+	name_en TEXT NOT NULL,
+	name_fa TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS moods (
+	id      TEXT PRIMARY KEY,
+	name_en TEXT NOT NULL,
+	name_fa TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS publishers (
+	id      TEXT PRIMARY KEY,
+	name_en TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS items (
+	id           TEXT PRIMARY KEY,
+	url          TEXT NOT NULL,
+	mood         TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	type         TEXT NOT NULL,
+	image        TEXT NOT NULL,
+	publisher_id TEXT REFERENCES publishers(id)
+);
+CREATE TABLE IF NOT EXISTS albums (
+	id      TEXT PRIMARY KEY,
+	item_id TEXT NOT NULL UNIQUE REFERENCES items(id) ON DELETE CASCADE,
+	name    TEXT NOT NULL,
+	image   TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS tracks (
+	item_id  TEXT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	id       TEXT NOT NULL,
+	album_id TEXT REFERENCES albums(id) ON DELETE CASCADE,
+	title    TEXT NOT NULL,
+	artist   TEXT NOT NULL,
+	album    TEXT NOT NULL,
+	info     TEXT NOT NULL,
+	image    TEXT NOT NULL,
+	duration TEXT NOT NULL,
+	mp3_link TEXT NOT NULL,
+	PRIMARY KEY (item_id, position)
+);
+CREATE INDEX IF NOT EXISTS tracks_id ON tracks(id);
+CREATE TABLE IF NOT EXISTS item_artists (
+	item_id   TEXT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+	artist_id TEXT NOT NULL REFERENCES artists(id),
+	PRIMARY KEY (item_id, artist_id)
+);
+CREATE TABLE IF NOT EXISTS item_genres (
+	item_id  TEXT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+	genre_id TEXT NOT NULL REFERENCES genres(id),
+	PRIMARY KEY (item_id, genre_id)
+);
+CREATE TABLE IF NOT EXISTS item_moods (
+	item_id TEXT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+	mood_id TEXT NOT NULL REFERENCES moods(id),
+	PRIMARY KEY (item_id, mood_id)
+);
+CREATE TABLE IF NOT EXISTS item_instruments (
+	item_id       TEXT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+	instrument_id TEXT NOT NULL REFERENCES instruments(id),
+	PRIMARY KEY (item_id, instrument_id)
+);
+`
+
+var _ Sink = (*SQLite)(nil)
+
+// SQLite stores the catalog in normalized tables of an embedded SQLite database.
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens or creates the database at path and creates the missing tables.
+func OpenSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog database: %w", err)
+	}
+	// workers write concurrently, SQLite has a single writer anyway
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create catalog tables: %w", err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+func (s *SQLite) SaveArtist(artist model.Artist) error {
+	_, err := s.db.Exec(`INSERT INTO artists (id, name_en, name_fa, description, img) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name_en = excluded.name_en, name_fa = excluded.name_fa,
+			description = excluded.description, img = excluded.img`,
+		artist.ID, artist.NameEN, artist.NameFA, artist.Description, artist.Img)
+	if err != nil {
+		return fmt.Errorf("failed to save artist %s: %w", artist.NameEN, err)
+	}
+	return nil
+}
+
+func (s *SQLite) SaveInstrument(instrument model.Instrument) error {
+	_, err := s.db.Exec(`INSERT INTO instruments (id, name_en, name_fa, description) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name_en = excluded.name_en, name_fa = excluded.name_fa,
+			description = excluded.description`,
+		instrument.ID, instrument.NameEN, instrument.NameFA, instrument.Description)
+	if err != nil {
+		return fmt.Errorf("failed to save instrument %s: %w", instrument.NameEN, err)
+	}
+	return nil
+}
+
+func (s *SQLite) SaveGenre(genre model.Genre) error {
+	_, err := s.db.Exec(`INSERT INTO genres (id, name_en, name_fa) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name_en = excluded.name_en, name_fa = excluded.name_fa`,
+		genre.ID, genre.NameEN, genre.NameFA)
+	if err != nil {
+		return fmt.Errorf("failed to save genre %s: %w", genre.NameEN, err)
+	}
+	return nil
+}
+
+func (s *SQLite) SaveMood(mood model.MoodData) error {
+	_, err := s.db.Exec(`INSERT INTO moods (id, name_en, name_fa) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name_en = excluded.name_en, name_fa = excluded.name_fa`,
+		mood.ID, mood.NameEN, mood.NameFA)
+	if err != nil {
+		return fmt.Errorf("failed to save mood %s: %w", mood.NameEN, err)
+	}
+	return nil
+}
+
+func (s *SQLite) SavePublisher(publisher model.Publisher) error {
+	_, err := s.db.Exec(`INSERT INTO publishers (id, name_en) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET name_en = excluded.name_en`,
+		publisher.ID, publisher.NameEN)
+	if err != nil {
+		return fmt.Errorf("failed to save publisher %s: %w", publisher.NameEN, err)
+	}
+	return nil
+}
+
+func (s *SQLite) SaveItem(ctx context.Context, item Item) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save item %s: %w", item.URL, err)
+	}
+	defer tx.Rollback()
+
+	if err := saveItem(tx, item); err != nil {
+		return fmt.Errorf("failed to save item %s: %w", item.URL, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save item %s: %w", item.URL, err)
+	}
+	return nil
+}
+
+func saveItem(tx *sql.Tx, item Item) error {
+	var publisherID sql.NullString
+	if item.PublisherID != "" {
+		if err := reference(tx, "publishers", item.PublisherID, item.Publisher); err != nil {
+			return err
+		}
+		publisherID = sql.NullString{String: item.PublisherID, Valid: true}
+	}
+
+	_, err := tx.Exec(`INSERT INTO items (id, url, mood, name, type, image, publisher_id) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET url = excluded.url, mood = excluded.mood, name = excluded.name,
+			type = excluded.type, image = excluded.image, publisher_id = excluded.publisher_id`,
+		item.ID, item.URL, item.Mood, item.Name, item.Type, item.Image, publisherID)
+	if err != nil {
+		return err
+	}
+
+	// everything hanging off the item is replaced by what was scraped this time
+	for _, stmt := range []string{
+		`DELETE FROM tracks WHERE item_id = ?`,
+		`DELETE FROM albums WHERE item_id = ?`,
+		`DELETE FROM item_artists WHERE item_id = ?`,
+		`DELETE FROM item_genres WHERE item_id = ?`,
+		`DELETE FROM item_moods WHERE item_id = ?`,
+		`DELETE FROM item_instruments WHERE item_id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, item.ID); err != nil {
+			return err
+		}
+	}
+
+	var albumID sql.NullString
+	if item.Album {
+		albumID = sql.NullString{String: model.EntityID(model.KindAlbum, item.URL), Valid: true}
+		_, err := tx.Exec(`INSERT INTO albums (id, item_id, name, image) VALUES (?, ?, ?, ?)`,
+			albumID, item.ID, item.Name, item.Image)
+		if err != nil {
+			return err
+		}
+	}
+	for i, track := range item.Tracks {
+		_, err := tx.Exec(`INSERT INTO tracks (item_id, position, id, album_id, title, artist, album, info, image, duration, mp3_link)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			item.ID, i+1, track.ID, albumID, track.Title, track.Artist, track.Album, track.Info, track.Image, track.Duration, track.MP3Link)
+		if err != nil {
+			return err
+		}
+	}
+
+	links := []struct {
+		table, linkTable, column string
+		ids, names               []string
+	}{
+		{"artists", "item_artists", "artist_id", item.ArtistIDs, item.Artists},
+		{"genres", "item_genres", "genre_id", item.GenreIDs, item.Genres},
+		{"moods", "item_moods", "mood_id", item.MoodIDs, item.Moods},
+		{"instruments", "item_instruments", "instrument_id", item.InstrumentIDs, item.Instruments},
+	}
+	for _, link := range links {
+		for i, id := range link.ids {
+			var name string
+			if i < len(link.names) {
+				name = link.names[i]
+			}
+			if err := reference(tx, link.table, id, name); err != nil {
+				return err
+			}
+			_, err = tx.Exec(`INSERT INTO `+link.linkTable+` (item_id, `+link.column+`) VALUES (?, ?) ON CONFLICT DO NOTHING`, item.ID, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reference makes sure the entity with id exists in table, inserting a row holding only
+// the name when the entity was not saved yet.
+func reference(tx *sql.Tx, table, id, name string) error {
+	_, err := tx.Exec(`INSERT INTO `+table+` (id, name_en) VALUES (?, ?) ON CONFLICT (id) DO NOTHING`, id, name)
+	if err != nil {
+		return fmt.Errorf("failed to reference %s %s: %w", table, id, err)
+	}
+	return nil
+}
+
+// Ping takes the write lock of the database and releases it right away.
+func (s *SQLite) Ping(ctx context.Context) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("catalog database is not available: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("catalog database is not writable: %w", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), "ROLLBACK"); err != nil {
+		return fmt.Errorf("catalog database is not writable: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
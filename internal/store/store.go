@@ -0,0 +1,148 @@
+// Package store persists the scraped catalog. Taxonomy entities are saved as they are
+// scraped, items are saved together with their tracks and links to the entities in one go.
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"song-sc/internal/model"
+)
+
+const (
+	KindDir    = "fs"
+	KindJSONL  = "jsonl"
+	KindSQLite = "sqlite"
+)
+
+// Sink is implemented by every catalog backend.
+type Sink interface {
+	SaveArtist(artist model.Artist) error
+	SaveInstrument(instrument model.Instrument) error
+	SaveGenre(genre model.Genre) error
+	SaveMood(mood model.MoodData) error
+	SavePublisher(publisher model.Publisher) error
+	// SaveItem stores the item, its tracks and its links atomically. Saving an item again
+	// replaces what was stored for it before. Nothing is written once ctx is done.
+	SaveItem(ctx context.Context, item Item) error
+	// Ping reports whether the sink can be written to.
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Item is a scraped item page. Entities are referenced by ID, their names are kept for
+// display. Entities that were not saved on their own are stored with the name only.
+type Item struct {
+	ID            string        `json:"id"`
+	URL           string        `json:"url"`
+	Mood          string        `json:"mood"`
+	Name          string        `json:"name"`
+	Type          string        `json:"type"`
+	Image         string        `json:"img"`
+	Album         bool          `json:"album"`
+	Publisher     string        `json:"publisher"`
+	PublisherID   string        `json:"publisher_id"`
+	Artists       []string      `json:"artists"`
+	ArtistIDs     []string      `json:"artist_ids"`
+	Genres        []string      `json:"genres"`
+	GenreIDs      []string      `json:"genre_ids"`
+	Moods         []string      `json:"moods"`
+	MoodIDs       []string      `json:"mood_ids"`
+	Instruments   []string      `json:"instruments"`
+	InstrumentIDs []string      `json:"instrument_ids"`
+	Tracks        []model.Track `json:"tracks"`
+}
+
+// AlbumRecord is the album stored for an album item.
+func (item Item) AlbumRecord() model.Album {
+	tracks := make([]model.AlbumTracks, 0, len(item.Tracks))
+	for _, t := range item.Tracks {
+		tracks = append(tracks, model.AlbumTracks{
+			ID:       t.ID,
+			Title:    t.Title,
+			Info:     t.Info,
+			Duration: t.Duration,
+			MP3Link:  t.MP3Link,
+		})
+	}
+	return model.Album{
+		ID:            model.EntityID(model.KindAlbum, item.URL),
+		Name:          item.Name,
+		Artists:       item.Artists,
+		ArtistIDs:     item.ArtistIDs,
+		Type:          "album",
+		Genres:        item.Genres,
+		GenreIDs:      item.GenreIDs,
+		Moods:         item.Moods,
+		MoodIDs:       item.MoodIDs,
+		Instruments:   item.Instruments,
+		InstrumentIDs: item.InstrumentIDs,
+		Publisher:     item.Publisher,
+		PublisherID:   item.PublisherID,
+		Image:         item.Image,
+		Tracks:        tracks,
+	}
+}
+
+// TrackRecords are the tracks of the item with the item's taxonomy filled in.
+func (item Item) TrackRecords() []model.Track {
+	tracks := make([]model.Track, 0, len(item.Tracks))
+	for _, track := range item.Tracks {
+		track.ItemID = item.ID
+		track.Type = item.Type
+		track.Genres = item.Genres
+		track.Moods = item.Moods
+		track.Instruments = item.Instruments
+		track.Publisher = item.Publisher
+		track.ArtistIDs = item.ArtistIDs
+		track.GenreIDs = item.GenreIDs
+		track.MoodIDs = item.MoodIDs
+		track.InstrumentIDs = item.InstrumentIDs
+		track.PublisherID = item.PublisherID
+		tracks = append(tracks, track)
+	}
+	return tracks
+}
+
+type Config struct {
+	Kind string
+	Path string
+}
+
+// ConfigFromEnv reads SINK and SINK_PATH.
+func ConfigFromEnv() Config {
+	return Config{
+		Kind: os.Getenv("SINK"),
+		Path: os.Getenv("SINK_PATH"),
+	}
+}
+
+// Open builds the sink of the configured kind. Without a path, the directory layout is
+// written to the working directory, the JSON Lines files to export/ and the database to
+// data/catalog.db.
+func Open(cfg Config) (Sink, error) {
+	switch cfg.Kind {
+	case "", KindDir:
+		if cfg.Path == "" {
+			cfg.Path = "."
+		}
+		return NewDir(cfg.Path)
+	case KindJSONL:
+		if cfg.Path == "" {
+			cfg.Path = "export"
+		}
+		return NewJSONL(cfg.Path)
+	case KindSQLite:
+		if cfg.Path == "" {
+			cfg.Path = filepath.Join("data", "catalog.db")
+		}
+		if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory '%s': %w", filepath.Dir(cfg.Path), err)
+		}
+		return OpenSQLite(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unknown sink %q", cfg.Kind)
+	}
+}
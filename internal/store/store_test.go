@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"song-sc/internal/model"
+)
+
+// testItems returns an album and a single linking to the entities of testEntities.
+func testItems() []Item {
+	artist := model.EntityID(model.KindArtist, "https://songsara.net/artist/a/")
+	genre := model.EntityID(model.KindGenre, "https://songsara.net/genre/g/")
+	mood := model.EntityID(model.KindMood, "https://songsara.net/moods/m/")
+	instrument := model.EntityID(model.KindInstrument, "https://songsara.net/instrument/i/")
+	publisher := model.EntityID(model.KindPublisher, "Publisher")
+
+	album := Item{
+		ID: model.EntityID(model.KindItem, "https://songsara.net/item/album/"), URL: "https://songsara.net/item/album/",
+		Mood: "Mood", Name: "Album / One", Type: "آلبوم", Image: "https://songsara.net/img/album.jpg", Album: true,
+		Publisher: "Publisher", PublisherID: publisher,
+		Artists: []string{"Artist"}, ArtistIDs: []string{artist},
+		Genres: []string{"Genre"}, GenreIDs: []string{genre},
+		Moods: []string{"Mood"}, MoodIDs: []string{mood},
+		Instruments: []string{"Instrument"}, InstrumentIDs: []string{instrument},
+	}
+	for _, title := range []string{"First", "Second"} {
+		link := "https://songsara.net/mp3/" + title + ".mp3"
+		album.Tracks = append(album.Tracks, model.Track{
+			ID: model.EntityID(model.KindTrack, link), Title: title, Artist: "Artist", Album: album.Name,
+			Info: "info", Image: album.Image, Duration: "03:00", MP3Link: link,
+		})
+	}
+
+	single := album
+	single.ID = model.EntityID(model.KindItem, "https://songsara.net/item/single/")
+	single.URL = "https://songsara.net/item/single/"
+	single.Name, single.Type, single.Album = "Single", "تک آهنگ", false
+	single.Instruments, single.InstrumentIDs = nil, nil
+	single.Tracks = []model.Track{{
+		ID: model.EntityID(model.KindTrack, "https://songsara.net/mp3/single.mp3"), Title: "Single", Artist: "Artist",
+		Album: "Single", Duration: "04:00", MP3Link: "https://songsara.net/mp3/single.mp3",
+	}}
+	return []Item{album, single}
+}
+
+func saveTestCatalog(t *testing.T, sink Sink, items []Item) {
+	t.Helper()
+	first := items[0]
+	entities := []error{
+		sink.SaveArtist(model.Artist{ID: first.ArtistIDs[0], NameEN: "Artist", NameFA: "هنرمند", Description: "d", Img: "i"}),
+		sink.SaveGenre(model.Genre{ID: first.GenreIDs[0], NameEN: "Genre", NameFA: "سبک"}),
+		sink.SaveMood(model.MoodData{ID: first.MoodIDs[0], NameEN: "Mood", NameFA: "حس"}),
+		sink.SaveInstrument(model.Instrument{ID: first.InstrumentIDs[0], NameEN: "Instrument", NameFA: "ساز", Description: "d"}),
+		sink.SavePublisher(model.Publisher{ID: first.PublisherID, NameEN: "Publisher"}),
+	}
+	for _, err := range entities {
+		if err != nil {
+			t.Fatalf("saving an entity failed: %v", err)
+		}
+	}
+	for _, item := range items {
+		if err := sink.SaveItem(context.Background(), item); err != nil {
+			t.Fatalf("SaveItem(%s) error = %v", item.Name, err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		kind, path string
+		// the directory layout does not keep the item an album track belongs to
+		exactTracks bool
+	}{
+		{KindDir, "catalog", false},
+		{KindJSONL, "export", true},
+		{KindSQLite, filepath.Join("data", "catalog.db"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			cfg := Config{Kind: tt.kind, Path: filepath.Join(t.TempDir(), tt.path)}
+			sink, err := Open(cfg)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if err := sink.Ping(context.Background()); err != nil {
+				t.Errorf("Ping() error = %v", err)
+			}
+
+			items := testItems()
+			saveTestCatalog(t, sink, items)
+			// saving the album again with one track less replaces what was stored for it
+			changed := items[0]
+			changed.Name = "Album Renamed"
+			changed.Tracks = changed.Tracks[:1]
+			if err := sink.SaveItem(context.Background(), changed); err != nil {
+				t.Fatalf("SaveItem() error = %v", err)
+			}
+			if err := sink.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			detected, err := DetectConfig(cfg.Path)
+			if err != nil || detected.Kind != tt.kind {
+				t.Errorf("DetectConfig() = %+v, %v, want kind %s", detected, err, tt.kind)
+			}
+			got, err := LoadCatalog(cfg)
+			if err != nil {
+				t.Fatalf("LoadCatalog() error = %v", err)
+			}
+
+			want := newCatalog()
+			want.addItem(changed)
+			want.addItem(items[1])
+			if !reflect.DeepEqual(got.Albums, want.Albums) {
+				t.Errorf("albums = %+v\nwant %+v", got.Albums, want.Albums)
+			}
+			if tt.exactTracks && !reflect.DeepEqual(got.Tracks, want.Tracks) {
+				t.Errorf("tracks = %+v\nwant %+v", got.Tracks, want.Tracks)
+			}
+			if !sameKeys(got.Tracks, want.Tracks) {
+				t.Errorf("track IDs = %v, want %v", keys(got.Tracks), keys(want.Tracks))
+			}
+
+			counts := []struct {
+				name string
+				got  int
+			}{
+				{"artists", len(got.Artists)},
+				{"genres", len(got.Genres)},
+				{"moods", len(got.Moods)},
+				{"instruments", len(got.Instruments)},
+				{"publishers", len(got.Publishers)},
+			}
+			for _, c := range counts {
+				if c.got != 1 {
+					t.Errorf("loaded %d %s, want 1", c.got, c.name)
+				}
+			}
+			if artist := got.Artists[items[0].ArtistIDs[0]]; artist.NameFA != "هنرمند" || artist.Description != "d" {
+				t.Errorf("artist = %+v, want the saved artist", artist)
+			}
+		})
+	}
+}
+
+func keys[V any](m map[string]V) []string {
+	var ks []string
+	for k := range m {
+		ks = append(ks, k)
+	}
+	return ks
+}
+
+func sameKeys[V any](a, b map[string]V) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
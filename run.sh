@@ -1,16 +1,34 @@
 #!/bin/sh
 set -e
 
-echo "Waiting until rabbit is ready"
-sleep 20
+# The script runs as PID 1, signals docker sends to the container reach it and not the
+# stage that is running, so they are passed on for the stage to shut down gracefully.
+child=""
+trap '[ -n "$child" ] && kill -TERM "$child" 2>/dev/null' TERM
+trap '[ -n "$child" ] && kill -INT "$child" 2>/dev/null' INT
 
+# stage runs a binary in the background and waits for it to exit, a stage stopped by a
+# signal fails the script so detail does not start after an interrupted discovery.
+stage() {
+	"$@" &
+	child=$!
+	status=0
+	wait "$child" || status=$?
+	# wait returns as soon as a trapped signal arrives, keep waiting for the stage itself
+	while kill -0 "$child" 2>/dev/null; do
+		status=0
+		wait "$child" || status=$?
+	done
+	child=""
+	return "$status"
+}
 
-echo "Starting Script 1..."
-/app/script1_app
-echo "Script 1 finished."
+# Both binaries wait for RabbitMQ to accept connections before they start working.
+# Arguments are passed to both, e.g. --full to recrawl items that did not change.
+echo "Starting discovery..."
+stage /app/discover "$@"
+echo "Discovery finished."
 
-sleep 2
-# Run the second application.
-echo "Starting Script 2..."
-/app/script2_app
-echo "Script 2 finished."
\ No newline at end of file
+echo "Starting detail scraping..."
+stage /app/detail "$@"
+echo "Detail scraping finished."